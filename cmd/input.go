@@ -44,10 +44,17 @@ type Input struct {
 	artifactServerPath                 string
 	artifactServerAddr                 string
 	artifactServerPort                 string
+	artifactRetentionDays              int
+	artifactStorage                    string
+	artifactAdminToken                 string
 	noCacheServer                      bool
 	cacheServerPath                    string
 	cacheServerAddr                    string
 	cacheServerPort                    uint16
+	cacheServerSizeLimit               string
+	cacheServerRepoSizeLimit           string
+	cacheServerAdminToken              string
+	runtimeTokenKey                    string
 	jsonLogger                         bool
 	noSkipCheckout                     bool
 	remoteName                         string
@@ -60,6 +67,8 @@ type Input struct {
 	networkName                        string
 	useNewActionCache                  bool
 	localRepository                    []string
+	baseRef                            string
+	summaryFile                        string
 }
 
 func (i *Input) resolve(path string) string {
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,11 +11,13 @@ import (
 	"runtime"
 	"runtime/debug"
 	"strings"
+	"sync"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/adrg/xdg"
 	"github.com/andreaskoch/go-fswatch"
 	docker_container "github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
 	"github.com/joho/godotenv"
 	gitignore "github.com/sabhiram/go-gitignore"
 	log "github.com/sirupsen/logrus"
@@ -24,6 +27,7 @@ import (
 	"github.com/nektos/act/pkg/artifactcache"
 	"github.com/nektos/act/pkg/artifacts"
 	"github.com/nektos/act/pkg/common"
+	"github.com/nektos/act/pkg/common/git"
 	"github.com/nektos/act/pkg/container"
 	"github.com/nektos/act/pkg/model"
 	"github.com/nektos/act/pkg/runner"
@@ -91,15 +95,24 @@ func Execute(ctx context.Context, version string) {
 	rootCmd.PersistentFlags().StringVarP(&input.artifactServerPath, "artifact-server-path", "", "", "Defines the path where the artifact server stores uploads and retrieves downloads from. If not specified the artifact server will not start.")
 	rootCmd.PersistentFlags().StringVarP(&input.artifactServerAddr, "artifact-server-addr", "", common.GetOutboundIP().String(), "Defines the address to which the artifact server binds.")
 	rootCmd.PersistentFlags().StringVarP(&input.artifactServerPort, "artifact-server-port", "", "34567", "Defines the port where the artifact server listens.")
+	rootCmd.PersistentFlags().IntVarP(&input.artifactRetentionDays, "artifact-retention-days", "", 0, "Defines the number of days after which the artifacts uploaded without retention-days are deleted. If 0 the artifacts are kept forever.")
+	rootCmd.PersistentFlags().StringVarP(&input.artifactStorage, "artifact-storage", "", "", "Defines where the artifact server stores the artifacts instead of the artifact server path, which still has to be set to start the artifact server, e.g.: s3://bucket/prefix?endpoint=http://minio:9000&path-style=true with the credentials in AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.")
+	rootCmd.PersistentFlags().StringVarP(&input.artifactAdminToken, "artifact-admin-token", "", "", "Defines the token of the artifact server endpoints which list and delete the artifacts of all the runs. The endpoints are not served without it.")
 	rootCmd.PersistentFlags().BoolVarP(&input.noSkipCheckout, "no-skip-checkout", "", false, "Do not skip actions/checkout")
 	rootCmd.PersistentFlags().BoolVarP(&input.noCacheServer, "no-cache-server", "", false, "Disable cache server")
 	rootCmd.PersistentFlags().StringVarP(&input.cacheServerPath, "cache-server-path", "", filepath.Join(CacheHomeDir, "actcache"), "Defines the path where the cache server stores caches.")
 	rootCmd.PersistentFlags().StringVarP(&input.cacheServerAddr, "cache-server-addr", "", common.GetOutboundIP().String(), "Defines the address to which the cache server binds.")
 	rootCmd.PersistentFlags().Uint16VarP(&input.cacheServerPort, "cache-server-port", "", 0, "Defines the port where the artifact server listens. 0 means a randomly available port.")
+	rootCmd.PersistentFlags().StringVarP(&input.cacheServerSizeLimit, "cache-server-size-limit", "", "", "Defines the total size of the caches, e.g.: 10g. The least recently used caches are evicted when it is exceeded. If not specified the size is not limited.")
+	rootCmd.PersistentFlags().StringVarP(&input.cacheServerRepoSizeLimit, "cache-server-repo-size-limit", "", "", "Defines the size of the caches of each repository, e.g.: 2g. The least recently used caches of the repository are evicted when it is exceeded. If not specified the size is not limited.")
+	rootCmd.PersistentFlags().StringVarP(&input.cacheServerAdminToken, "cache-server-admin-token", "", "", "Defines the token of the cache server endpoint which shows the usage of the caches of all the repositories. The endpoint is not served without it.")
+	rootCmd.PersistentFlags().StringVarP(&input.runtimeTokenKey, "runtime-token-key", "", "", "Defines the key of at least 16 bytes which signs ACTIONS_RUNTIME_TOKEN, the artifact and cache servers of other act processes with the same key accept the tokens, e.g. an external cache server in ACTIONS_CACHE_URL. If not specified a random key is used.")
 	rootCmd.PersistentFlags().StringVarP(&input.actionCachePath, "action-cache-path", "", filepath.Join(CacheHomeDir, "act"), "Defines the path where the actions get cached and host workspaces created.")
 	rootCmd.PersistentFlags().BoolVarP(&input.actionOfflineMode, "action-offline-mode", "", false, "If action contents exists, it will not be fetch and pull again. If turn on this,will turn off force pull")
 	rootCmd.PersistentFlags().StringVarP(&input.networkName, "network", "", "host", "Sets a docker network name. Defaults to host.")
 	rootCmd.PersistentFlags().BoolVarP(&input.useNewActionCache, "use-new-action-cache", "", false, "Enable using the new Action Cache for storing Actions locally")
+	rootCmd.PersistentFlags().StringVarP(&input.baseRef, "base-ref", "", "", "git ref to compare HEAD with to find the changed files used by the paths filters of the workflows (e.g. origin/main). If not specified, paths filters are not applied.")
+	rootCmd.PersistentFlags().StringVarP(&input.summaryFile, "summary-file", "", "", "file to write the job summaries to, which are written by the steps to GITHUB_STEP_SUMMARY")
 	rootCmd.PersistentFlags().StringArrayVarP(&input.localRepository, "local-repository", "", []string{}, "Replaces the specified repository and ref with a local folder (e.g. https://github.com/test/test@v0=/home/act/test or test/test@v0=/home/act/test, the latter matches any hosts or protocols)")
 	rootCmd.SetArgs(args())
 
@@ -469,24 +482,24 @@ func newRunCommand(ctx context.Context, input *Input) func(*cobra.Command, []str
 			eventName = "push"
 		}
 
+		// check to see if the main branch was defined
+		defaultbranch, err := cmd.Flags().GetString("defaultbranch")
+		if err != nil {
+			return err
+		}
+
 		// build the plan for this run
 		if jobID != "" {
 			log.Debugf("Planning job: %s", jobID)
 			plan, plannerErr = planner.PlanJob(jobID)
 		} else {
 			log.Debugf("Planning jobs for event: %s", eventName)
-			plan, plannerErr = planner.PlanEvent(eventName)
+			plan, plannerErr = planner.PlanEventWithFilter(eventName, newEventFilter(ctx, input, eventName, defaultbranch))
 		}
 		if plan == nil && plannerErr != nil {
 			return plannerErr
 		}
 
-		// check to see if the main branch was defined
-		defaultbranch, err := cmd.Flags().GetString("defaultbranch")
-		if err != nil {
-			return err
-		}
-
 		// Check if platforms flag is set, if not, run default image survey
 		if len(input.platforms) == 0 {
 			cfgFound := false
@@ -587,18 +600,58 @@ func newRunCommand(ctx context.Context, input *Input) func(*cobra.Command, []str
 				}
 			}
 		}
+		if input.summaryFile != "" {
+			config.JobSummaryHandler, err = newJobSummaryFileHandler(input.summaryFile)
+			if err != nil {
+				return err
+			}
+		}
+		if input.runtimeTokenKey != "" {
+			if err := common.SetAuthorizationTokenKey([]byte(input.runtimeTokenKey)); err != nil {
+				return fmt.Errorf("invalid runtime token key: %w", err)
+			}
+		}
 		r, err := runner.New(config)
 		if err != nil {
 			return err
 		}
 
-		cancel := artifacts.Serve(ctx, input.artifactServerPath, input.artifactServerAddr, input.artifactServerPort)
+		artifactOptions := []artifacts.ServeOption{artifacts.WithRetentionDays(input.artifactRetentionDays)}
+		if input.artifactStorage != "" {
+			storage, err := artifacts.NewStorage(input.artifactStorage)
+			if err != nil {
+				return err
+			}
+			artifactOptions = append(artifactOptions, artifacts.WithStorage(storage))
+		}
+		if input.artifactAdminToken != "" {
+			artifactOptions = append(artifactOptions, artifacts.WithAdminToken(input.artifactAdminToken))
+		}
+		cancel := artifacts.Serve(ctx, input.artifactServerPath, input.artifactServerAddr, input.artifactServerPort, artifactOptions...)
 
 		const cacheURLKey = "ACTIONS_CACHE_URL"
 		var cacheHandler *artifactcache.Handler
 		if !input.noCacheServer && envs[cacheURLKey] == "" {
 			var err error
-			cacheHandler, err = artifactcache.StartHandler(input.cacheServerPath, input.cacheServerAddr, input.cacheServerPort, common.Logger(ctx))
+			var cacheOptions []artifactcache.HandlerOption
+			if input.cacheServerSizeLimit != "" {
+				size, err := units.RAMInBytes(input.cacheServerSizeLimit)
+				if err != nil {
+					return fmt.Errorf("invalid cache server size limit: %w", err)
+				}
+				cacheOptions = append(cacheOptions, artifactcache.WithSizeLimit(size))
+			}
+			if input.cacheServerRepoSizeLimit != "" {
+				size, err := units.RAMInBytes(input.cacheServerRepoSizeLimit)
+				if err != nil {
+					return fmt.Errorf("invalid cache server repo size limit: %w", err)
+				}
+				cacheOptions = append(cacheOptions, artifactcache.WithRepoSizeLimit(size))
+			}
+			if input.cacheServerAdminToken != "" {
+				cacheOptions = append(cacheOptions, artifactcache.WithAdminToken(input.cacheServerAdminToken))
+			}
+			cacheHandler, err = artifactcache.StartHandler(input.cacheServerPath, input.cacheServerAddr, input.cacheServerPort, common.Logger(ctx), cacheOptions...)
 			if err != nil {
 				return err
 			}
@@ -629,6 +682,72 @@ func newRunCommand(ctx context.Context, input *Input) func(*cobra.Command, []str
 	}
 }
 
+// newJobSummaryFileHandler truncates the file and returns a handler which appends the job summaries to it
+func newJobSummaryFileHandler(path string) (runner.JobSummaryHandler, error) {
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		return nil, fmt.Errorf("unable to create summary file: %w", err)
+	}
+	var mu sync.Mutex
+	return func(jobID, jobName, summary string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Errorf("Unable to write summary of job '%s': %v", jobName, err)
+			return
+		}
+		defer f.Close()
+		if !strings.HasSuffix(summary, "\n") {
+			summary += "\n"
+		}
+		if _, err := fmt.Fprintf(f, "<!-- %s -->\n%s", jobName, summary); err != nil {
+			log.Errorf("Unable to write summary of job '%s': %v", jobName, err)
+		}
+	}, nil
+}
+
+// newEventFilter determines the ref and the changed files of the event,
+// which are matched against the branches, tags and paths filters of the workflows
+func newEventFilter(ctx context.Context, input *Input, eventName string, defaultBranch string) *model.EventFilter {
+	ghc := &model.GithubContext{
+		EventName: eventName,
+		Event:     map[string]interface{}{},
+	}
+	if input.eventPath != "" {
+		if content, err := os.ReadFile(input.EventPath()); err != nil {
+			log.Warnf("Unable to read event for filtering: %v", err)
+		} else if err := json.Unmarshal(content, &ghc.Event); err != nil {
+			log.Warnf("Unable to parse event for filtering: %v", err)
+		}
+	}
+
+	filter := &model.EventFilter{}
+	switch eventName {
+	case "pull_request", "pull_request_target":
+		// the branch filters of pull requests are matched against the base branch
+		ghc.SetBaseAndHeadRef()
+		if ghc.BaseRef != "" {
+			filter.Ref = "refs/heads/" + ghc.BaseRef
+		}
+	default:
+		ghc.SetRef(ctx, defaultBranch, input.Workdir())
+		filter.Ref = ghc.Ref
+	}
+
+	if input.baseRef != "" {
+		files, err := git.FindChangedFiles(ctx, input.Workdir(), input.baseRef)
+		if err != nil {
+			log.Warnf("Unable to find changed files, paths filters will not be applied: %v", err)
+		} else {
+			log.Debugf("Changed files compared to '%s': %v", input.baseRef, files)
+			filter.ChangedFiles = files
+		}
+	}
+
+	return filter
+}
+
 func defaultImageSurvey(actrc string) error {
 	var answer string
 	confirmation := &survey.Select{
@@ -1,6 +1,7 @@
 package artifactcache
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -38,11 +39,23 @@ type Handler struct {
 	gcing atomic.Bool
 	gcAt  time.Time
 
+	evicting      atomic.Bool
+	sizeLimit     int64
+	repoSizeLimit int64
+	adminToken    string
+
+	trustScopeHeaders bool
+	// key signs the archive locations of the caches
+	key []byte
+
 	outboundIP string
 }
 
-func StartHandler(dir, outboundIP string, port uint16, logger logrus.FieldLogger) (*Handler, error) {
+func StartHandler(dir, outboundIP string, port uint16, logger logrus.FieldLogger, options ...HandlerOption) (*Handler, error) {
 	h := &Handler{}
+	for _, o := range options {
+		o(h)
+	}
 
 	if logger == nil {
 		discard := logrus.New()
@@ -52,6 +65,11 @@ func StartHandler(dir, outboundIP string, port uint16, logger logrus.FieldLogger
 	logger = logger.WithField("module", "artifactcache")
 	h.logger = logger
 
+	h.key = make([]byte, 32)
+	if _, err := rand.Read(h.key); err != nil {
+		return nil, err
+	}
+
 	if dir == "" {
 		home, err := os.UserHomeDir()
 		if err != nil {
@@ -81,15 +99,19 @@ func StartHandler(dir, outboundIP string, port uint16, logger logrus.FieldLogger
 
 	router := httprouter.New()
 	router.GET(urlBase+"/cache", h.middleware(h.find))
-	router.POST(urlBase+"/caches", h.middleware(h.reserve))
+	router.POST(urlBase+"/caches", h.middleware(h.evictAfter(h.reserve)))
 	router.PATCH(urlBase+"/caches/:id", h.middleware(h.upload))
-	router.POST(urlBase+"/caches/:id", h.middleware(h.commit))
+	router.POST(urlBase+"/caches/:id", h.middleware(h.evictAfter(h.commit)))
 	router.GET(urlBase+"/artifacts/:id", h.middleware(h.get))
 	router.POST(urlBase+"/clean", h.middleware(h.clean))
+	if h.adminToken != "" {
+		router.GET(urlBase+"/stats", h.middleware(h.stats))
+	}
 
 	h.router = router
 
 	h.gcCache()
+	h.evictCache()
 
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port)) // listen on all interfaces
 	if err != nil {
@@ -162,6 +184,11 @@ func (h *Handler) find(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 		keys[i] = strings.ToLower(key)
 	}
 	version := r.URL.Query().Get("version")
+	scope, err := h.getScope(r)
+	if err != nil {
+		h.responseJSON(w, r, 401, err)
+		return
+	}
 
 	db, err := h.openDB()
 	if err != nil {
@@ -170,7 +197,7 @@ func (h *Handler) find(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 	}
 	defer db.Close()
 
-	cache, err := findCache(db, keys, version)
+	cache, err := findCache(db, scope, keys, version)
 	if err != nil {
 		h.responseJSON(w, r, 500, err)
 		return
@@ -190,7 +217,7 @@ func (h *Handler) find(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 	}
 	h.responseJSON(w, r, 200, map[string]any{
 		"result":          "hit",
-		"archiveLocation": fmt.Sprintf("%s%s/artifacts/%d", h.ExternalURL(), urlBase, cache.ID),
+		"archiveLocation": h.archiveLocation(cache.ID),
 		"cacheKey":        cache.Key,
 	})
 }
@@ -205,7 +232,18 @@ func (h *Handler) reserve(w http.ResponseWriter, r *http.Request, _ httprouter.P
 	// cache keys are case insensitive
 	api.Key = strings.ToLower(api.Key)
 
+	scope, err := h.getScope(r)
+	if err != nil {
+		h.responseJSON(w, r, 401, err)
+		return
+	}
+	if err := h.checkCacheSize(api.Size); err != nil {
+		h.responseJSON(w, r, 400, err)
+		return
+	}
 	cache := api.ToCache()
+	cache.Repo = scope.Repository
+	cache.Ref = scope.Ref
 	db, err := h.openDB()
 	if err != nil {
 		h.responseJSON(w, r, 500, err)
@@ -233,6 +271,12 @@ func (h *Handler) upload(w http.ResponseWriter, r *http.Request, params httprout
 		return
 	}
 
+	scope, err := h.getScope(r)
+	if err != nil {
+		h.responseJSON(w, r, 401, err)
+		return
+	}
+
 	cache := &Cache{}
 	db, err := h.openDB()
 	if err != nil {
@@ -249,6 +293,10 @@ func (h *Handler) upload(w http.ResponseWriter, r *http.Request, params httprout
 		return
 	}
 
+	if !inScope(cache, scope) {
+		h.responseJSON(w, r, 403, fmt.Errorf("cache %d: reserved in another scope", id))
+		return
+	}
 	if cache.Complete {
 		h.responseJSON(w, r, 400, fmt.Errorf("cache %v %q: already complete", cache.ID, cache.Key))
 		return
@@ -274,6 +322,12 @@ func (h *Handler) commit(w http.ResponseWriter, r *http.Request, params httprout
 		return
 	}
 
+	scope, err := h.getScope(r)
+	if err != nil {
+		h.responseJSON(w, r, 401, err)
+		return
+	}
+
 	cache := &Cache{}
 	db, err := h.openDB()
 	if err != nil {
@@ -290,6 +344,10 @@ func (h *Handler) commit(w http.ResponseWriter, r *http.Request, params httprout
 		return
 	}
 
+	if !inScope(cache, scope) {
+		h.responseJSON(w, r, 403, fmt.Errorf("cache %d: reserved in another scope", id))
+		return
+	}
 	if cache.Complete {
 		h.responseJSON(w, r, 400, fmt.Errorf("cache %v %q: already complete", cache.ID, cache.Key))
 		return
@@ -312,6 +370,14 @@ func (h *Handler) commit(w http.ResponseWriter, r *http.Request, params httprout
 	}
 	defer db.Close()
 
+	// the size is only known now if the request of the reservation doesn't specify it
+	if err := h.checkCacheSize(size); err != nil {
+		h.storage.Remove(cache.ID)
+		_ = db.Delete(cache.ID, cache)
+		h.responseJSON(w, r, 400, err)
+		return
+	}
+
 	cache.Complete = true
 	if err := db.Update(cache.ID, cache); err != nil {
 		h.responseJSON(w, r, 500, err)
@@ -328,6 +394,10 @@ func (h *Handler) get(w http.ResponseWriter, r *http.Request, params httprouter.
 		h.responseJSON(w, r, 400, err)
 		return
 	}
+	if err := h.verifyArchiveLocation(r, uint64(id)); err != nil {
+		h.responseJSON(w, r, 403, err)
+		return
+	}
 	h.useCache(id)
 	h.storage.Serve(w, r, uint64(id))
 }
@@ -348,8 +418,29 @@ func (h *Handler) middleware(handler httprouter.Handle) httprouter.Handle {
 	}
 }
 
+// evictAfter evicts the caches after the requests which may grow the sizes of the caches,
+// it is not done after every request since it locks the database
+func (h *Handler) evictAfter(handler httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		handler(w, r, params)
+		go h.evictCache()
+	}
+}
+
 // if not found, return (nil, nil) instead of an error.
-func findCache(db *bolthold.Store, keys []string, version string) (*Cache, error) {
+// The refs of the scope are searched in order, like GitHub searches the ref of the run first
+// and then the base ref and the default branch.
+func findCache(db *bolthold.Store, scope *common.CacheScope, keys []string, version string) (*Cache, error) {
+	for _, ref := range scope.Refs() {
+		cache, err := findCacheOfRef(db, scope.Repository, ref, keys, version)
+		if err != nil || cache != nil {
+			return cache, err
+		}
+	}
+	return nil, nil
+}
+
+func findCacheOfRef(db *bolthold.Store, repo, ref string, keys []string, version string) (*Cache, error) {
 	cache := &Cache{}
 	for _, prefix := range keys {
 		// if a key in the list matches exactly, don't return partial matches
@@ -357,6 +448,8 @@ func findCache(db *bolthold.Store, keys []string, version string) (*Cache, error
 			bolthold.Where("Key").Eq(prefix).
 				And("Version").Eq(version).
 				And("Complete").Eq(true).
+				And("Repo").Eq(repo).
+				And("Ref").Eq(ref).
 				SortBy("CreatedAt").Reverse()); err == nil || !errors.Is(err, bolthold.ErrNotFound) {
 			if err != nil {
 				return nil, fmt.Errorf("find cache: %w", err)
@@ -372,6 +465,8 @@ func findCache(db *bolthold.Store, keys []string, version string) (*Cache, error
 			bolthold.Where("Key").RegExp(re).
 				And("Version").Eq(version).
 				And("Complete").Eq(true).
+				And("Repo").Eq(repo).
+				And("Ref").Eq(ref).
 				SortBy("CreatedAt").Reverse()); err != nil {
 			if errors.Is(err, bolthold.ErrNotFound) {
 				continue
@@ -489,12 +584,12 @@ func (h *Handler) gcCache() {
 		}
 	}
 
-	// Remove the old caches with the same key and version, keep the latest one.
+	// Remove the old caches with the same key and version in the same scope, keep the latest one.
 	// Also keep the olds which have been used recently for a while in case of the cache is still in use.
 	if results, err := db.FindAggregate(
 		&Cache{},
 		bolthold.Where("Complete").Eq(true),
-		"Key", "Version",
+		"Repo", "Ref", "Key", "Version",
 	); err != nil {
 		h.logger.Warnf("find aggregate caches: %v", err)
 	} else {
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -16,6 +19,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/timshannon/bolthold"
 	"go.etcd.io/bbolt"
+
+	"github.com/nektos/act/pkg/common"
 )
 
 func TestHandler(t *testing.T) {
@@ -334,13 +339,13 @@ func TestHandler(t *testing.T) {
 	})
 
 	t.Run("get with not exist id", func(t *testing.T) {
-		resp, err := http.Get(fmt.Sprintf("%s/artifacts/%d", base, 100))
+		resp, err := http.Get(handler.archiveLocation(100))
 		require.NoError(t, err)
 		require.Equal(t, 404, resp.StatusCode)
 	})
 
 	t.Run("get with not exist id", func(t *testing.T) {
-		resp, err := http.Get(fmt.Sprintf("%s/artifacts/%d", base, 100))
+		resp, err := http.Get(handler.archiveLocation(100))
 		require.NoError(t, err)
 		require.Equal(t, 404, resp.StatusCode)
 	})
@@ -668,6 +673,19 @@ func TestHandler_gcCache(t *testing.T) {
 			},
 			Kept: false,
 		},
+		{
+			// should be kept, since the newer edition is in another scope.
+			Cache: &Cache{
+				Repo:      "owner/repo",
+				Ref:       "refs/heads/main",
+				Key:       "test_key_1",
+				Version:   "test_version",
+				Complete:  true,
+				UsedAt:    now.Add(-(keepOld + time.Second)).Unix(),
+				CreatedAt: now.Add(-(time.Hour + time.Second)).Unix(),
+			},
+			Kept: true,
+		},
 	}
 
 	db, err := handler.openDB()
@@ -695,3 +713,334 @@ func TestHandler_gcCache(t *testing.T) {
 	}
 	require.NoError(t, db.Close())
 }
+
+func TestHandler_scope(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "artifactcache")
+	handler, err := StartHandler(dir, "", 0, nil, WithTrustedScopeHeaders())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, handler.Close())
+	}()
+
+	base := fmt.Sprintf("%s%s", handler.ExternalURL(), urlBase)
+	version := "c19da02a2bd7e77277f1ac29ab45c09b7d46a4ee758284e26bb3045ad11d9d20"
+
+	withHeaders := func(repo, ref string, restoreRefs ...string) http.Header {
+		header := http.Header{}
+		header.Set(headerRepo, repo)
+		header.Set(headerRef, ref)
+		header.Set(headerRestoreRefs, strings.Join(restoreRefs, ","))
+		return header
+	}
+	withToken := func(repo, ref string, restoreRefs ...string) http.Header {
+		token, err := common.CreateAuthorizationToken("1", "build", &common.CacheScope{
+			Repository:  repo,
+			Ref:         ref,
+			RestoreRefs: restoreRefs,
+		})
+		require.NoError(t, err)
+		header := http.Header{}
+		header.Set("Authorization", "Bearer "+token)
+		return header
+	}
+	do := func(method, url string, header http.Header, body io.Reader) *http.Response {
+		req, err := http.NewRequest(method, url, body)
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	save := func(header http.Header, key, content string) {
+		body, err := json.Marshal(&Request{Key: key, Version: version, Size: int64(len(content))})
+		require.NoError(t, err)
+		resp := do(http.MethodPost, base+"/caches", header, bytes.NewReader(body))
+		require.Equal(t, 200, resp.StatusCode)
+		got := struct {
+			CacheID uint64 `json:"cacheId"`
+		}{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+
+		header = header.Clone()
+		header.Set("Content-Type", "application/octet-stream")
+		header.Set("Content-Range", fmt.Sprintf("bytes 0-%d/*", len(content)-1))
+		resp = do(http.MethodPatch, fmt.Sprintf("%s/caches/%d", base, got.CacheID), header, strings.NewReader(content))
+		require.Equal(t, 200, resp.StatusCode)
+		resp = do(http.MethodPost, fmt.Sprintf("%s/caches/%d", base, got.CacheID), header, nil)
+		require.Equal(t, 200, resp.StatusCode)
+	}
+	restore := func(header http.Header, keys string) string {
+		resp := do(http.MethodGet, fmt.Sprintf("%s/cache?keys=%s&version=%s", base, keys, version), header, nil)
+		if resp.StatusCode == 204 {
+			return ""
+		}
+		require.Equal(t, 200, resp.StatusCode)
+		got := struct {
+			ArchiveLocation string `json:"archiveLocation"`
+		}{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		resp, err := http.Get(got.ArchiveLocation)
+		require.NoError(t, err)
+		content, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	save(withHeaders("owner/repo", "refs/heads/main"), "deps-1", "main")
+	save(withToken("owner/repo", "refs/heads/feature", "refs/heads/main"), "deps-2", "feature")
+	save(withToken("owner/repo", "refs/pull/1/merge", "refs/heads/feature", "refs/heads/main"), "deps-3", "pull")
+	save(withHeaders("owner/other", "refs/heads/main"), "deps-4", "other")
+	save(http.Header{}, "deps-5", "unscoped")
+
+	for _, header := range []func(string, string, ...string) http.Header{withHeaders, withToken} {
+		// the caches of the ref are restored first, and then the ones of the restore refs in order
+		assert.Equal(t, "pull", restore(header("owner/repo", "refs/pull/1/merge", "refs/heads/feature", "refs/heads/main"), "deps"))
+		assert.Equal(t, "feature", restore(header("owner/repo", "refs/pull/2/merge", "refs/heads/feature", "refs/heads/main"), "deps"))
+		assert.Equal(t, "main", restore(header("owner/repo", "refs/pull/3/merge", "refs/heads/other", "refs/heads/main"), "deps"))
+		assert.Equal(t, "main", restore(header("owner/repo", "refs/heads/main"), "deps"))
+		// the default branch can not restore the caches of other branches
+		assert.Equal(t, "", restore(header("owner/repo", "refs/heads/main"), "deps-2"))
+		assert.Equal(t, "other", restore(header("owner/other", "refs/heads/main"), "deps"))
+		assert.Equal(t, "", restore(header("owner/another", "refs/heads/main"), "deps"))
+	}
+	assert.Equal(t, "unscoped", restore(http.Header{}, "deps"))
+
+	t.Run("upload in another scope", func(t *testing.T) {
+		body, err := json.Marshal(&Request{Key: "deps-6", Version: version, Size: 1})
+		require.NoError(t, err)
+		resp := do(http.MethodPost, base+"/caches", withHeaders("owner/repo", "refs/heads/main"), bytes.NewReader(body))
+		require.Equal(t, 200, resp.StatusCode)
+		got := struct {
+			CacheID uint64 `json:"cacheId"`
+		}{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+
+		header := withHeaders("owner/repo", "refs/heads/feature")
+		header.Set("Content-Type", "application/octet-stream")
+		header.Set("Content-Range", "bytes 0-0/*")
+		resp = do(http.MethodPatch, fmt.Sprintf("%s/caches/%d", base, got.CacheID), header, strings.NewReader("x"))
+		assert.Equal(t, 403, resp.StatusCode)
+		resp = do(http.MethodPost, fmt.Sprintf("%s/caches/%d", base, got.CacheID), header, nil)
+		assert.Equal(t, 403, resp.StatusCode)
+	})
+
+	t.Run("download by id", func(t *testing.T) {
+		resp := do(http.MethodGet, fmt.Sprintf("%s/cache?keys=deps-1&version=%s", base, version), withToken("owner/repo", "refs/heads/main"), nil)
+		require.Equal(t, 200, resp.StatusCode)
+		got := struct {
+			ArchiveLocation string `json:"archiveLocation"`
+		}{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		location, err := url.Parse(got.ArchiveLocation)
+		require.NoError(t, err)
+
+		// the caches of other scopes can not be downloaded by their ids without the signed location
+		unsigned := *location
+		unsigned.RawQuery = ""
+		resp = do(http.MethodGet, unsigned.String(), withToken("evil/repo", "refs/heads/main"), nil)
+		assert.Equal(t, 403, resp.StatusCode)
+		resp = do(http.MethodGet, unsigned.String(), http.Header{}, nil)
+		assert.Equal(t, 403, resp.StatusCode)
+
+		// the signature of a cache is not valid for another one
+		other := *location
+		other.Path = path.Join(path.Dir(location.Path), "2")
+		require.NotEqual(t, location.Path, other.Path)
+		resp = do(http.MethodGet, other.String(), http.Header{}, nil)
+		assert.Equal(t, 403, resp.StatusCode)
+
+		resp = do(http.MethodGet, location.String(), http.Header{}, nil)
+		assert.Equal(t, 200, resp.StatusCode)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		for _, auth := range []string{"Bearer invalid", "Basic dXNlcjpwYXNz"} {
+			header := http.Header{}
+			header.Set("Authorization", auth)
+			resp := do(http.MethodGet, fmt.Sprintf("%s/cache?keys=deps&version=%s", base, version), header, nil)
+			assert.Equal(t, 401, resp.StatusCode, auth)
+			body, err := json.Marshal(&Request{Key: "deps-7", Version: version, Size: 1})
+			require.NoError(t, err)
+			resp = do(http.MethodPost, base+"/caches", header, bytes.NewReader(body))
+			assert.Equal(t, 401, resp.StatusCode, auth)
+		}
+	})
+}
+
+func TestHandler_untrustedScopeHeaders(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "artifactcache")
+	handler, err := StartHandler(dir, "", 0, nil)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, handler.Close())
+	}()
+
+	token, err := common.CreateAuthorizationToken("1", "build", &common.CacheScope{
+		Repository: "owner/repo",
+		Ref:        "refs/heads/feature",
+	})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(headerRepo, "owner/repo")
+	req.Header.Set(headerRef, "refs/heads/main")
+
+	// the headers can not save the caches of a job to another ref
+	scope, err := handler.getScope(req)
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/feature", scope.Ref)
+
+	req.Header.Del("Authorization")
+	scope, err = handler.getScope(req)
+	require.NoError(t, err)
+	assert.Equal(t, &common.CacheScope{}, scope)
+}
+
+func TestHandler_evictCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "artifactcache")
+	handler, err := StartHandler(dir, "", 0, nil, WithSizeLimit(100), WithRepoSizeLimit(70), WithAdminToken("admin"))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, handler.Close())
+	}()
+
+	now := time.Now()
+	cases := []struct {
+		Cache *Cache
+		Kept  bool
+	}{
+		{
+			// should be removed, since the total size is exceeded and it's the least recently used.
+			Cache: &Cache{Key: "unscoped", Size: 10, Complete: true, UsedAt: now.Add(-6 * time.Hour).Unix()},
+			Kept:  false,
+		},
+		{
+			// should be removed, since the size of the repository is exceeded and it's the least recently used of it.
+			Cache: &Cache{Repo: "owner/a", Key: "a_1", Size: 30, Complete: true, UsedAt: now.Add(-5 * time.Hour).Unix()},
+			Kept:  false,
+		},
+		{
+			Cache: &Cache{Repo: "owner/a", Key: "a_2", Size: 30, Complete: true, UsedAt: now.Add(-time.Hour).Unix()},
+			Kept:  true,
+		},
+		{
+			Cache: &Cache{Repo: "owner/a", Key: "a_3", Size: 30, Complete: true, UsedAt: now.Add(-2 * time.Hour).Unix()},
+			Kept:  true,
+		},
+		{
+			// should be removed, since the total size is exceeded with the cache which is being uploaded.
+			Cache: &Cache{Repo: "owner/b", Key: "b_1", Size: 20, Complete: true, UsedAt: now.Add(-4 * time.Hour).Unix()},
+			Kept:  false,
+		},
+		{
+			// should be kept, since the sizes are in the limits after the older ones have been removed.
+			Cache: &Cache{Repo: "owner/b", Key: "b_2", Size: 20, Complete: true, UsedAt: now.Add(-3 * time.Hour).Unix()},
+			Kept:  true,
+		},
+		{
+			// should be kept, since it's not complete, but it's counted in the sizes.
+			Cache: &Cache{Repo: "owner/b", Key: "b_3", Size: 20, Complete: false, UsedAt: now.Add(-10 * time.Hour).Unix()},
+			Kept:  true,
+		},
+	}
+
+	db, err := handler.openDB()
+	require.NoError(t, err)
+	for _, c := range cases {
+		require.NoError(t, insertCache(db, c.Cache))
+		if c.Cache.Complete {
+			require.NoError(t, handler.storage.Write(c.Cache.ID, 0, bytes.NewReader(make([]byte, c.Cache.Size))))
+			_, err := handler.storage.Commit(c.Cache.ID, c.Cache.Size)
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, db.Close())
+
+	handler.evictCache()
+
+	db, err = handler.openDB()
+	require.NoError(t, err)
+	for i, v := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, v.Cache.Key), func(t *testing.T) {
+			cache := &Cache{}
+			err = db.Get(v.Cache.ID, cache)
+			if v.Kept {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, bolthold.ErrNotFound)
+			}
+			ok, err := handler.storage.Exist(v.Cache.ID)
+			require.NoError(t, err)
+			assert.Equal(t, v.Kept && v.Cache.Complete, ok)
+		})
+	}
+	require.NoError(t, db.Close())
+
+	t.Run("stats", func(t *testing.T) {
+		getStats := func(token string) *http.Response {
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s%s/stats", handler.ExternalURL(), urlBase), nil)
+			require.NoError(t, err)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			return resp
+		}
+		assert.Equal(t, 401, getStats("").StatusCode)
+		assert.Equal(t, 401, getStats("wrong").StatusCode)
+
+		resp := getStats("admin")
+		require.Equal(t, 200, resp.StatusCode)
+		stats := &Stats{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(stats))
+		assert.Equal(t, &Stats{
+			UsageStats:    UsageStats{Count: 3, Size: 80},
+			SizeLimit:     100,
+			RepoSizeLimit: 70,
+			DiskSize:      80,
+			Repos: map[string]*UsageStats{
+				"owner/a": {Count: 2, Size: 60},
+				"owner/b": {Count: 1, Size: 20},
+			},
+		}, stats)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		base := fmt.Sprintf("%s%s", handler.ExternalURL(), urlBase)
+		version := "c19da02a2bd7e77277f1ac29ab45c09b7d46a4ee758284e26bb3045ad11d9d20"
+
+		// the size of the repository is exceeded by the cache alone
+		body, err := json.Marshal(&Request{Key: "too-large", Version: version, Size: 71})
+		require.NoError(t, err)
+		resp, err := http.Post(base+"/caches", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+
+		// the size is checked when the cache is committed if it's unknown before
+		body, err = json.Marshal(&Request{Key: "too-large", Version: version})
+		require.NoError(t, err)
+		resp, err = http.Post(base+"/caches", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+		got := struct {
+			CacheID uint64 `json:"cacheId"`
+		}{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/caches/%d", base, got.CacheID), bytes.NewReader(make([]byte, 71)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("Content-Range", "bytes 0-70/*")
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+		resp, err = http.Post(fmt.Sprintf("%s/caches/%d", base, got.CacheID), "application/json", nil)
+		require.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+		ok, err := handler.storage.Exist(got.CacheID)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,176 @@
+package artifactcache
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/timshannon/bolthold"
+)
+
+// HandlerOption configures the cache handler
+type HandlerOption func(h *Handler)
+
+// WithSizeLimit limits the total size of the caches, the least recently used caches are evicted
+// when it is exceeded, 0 means no limit
+func WithSizeLimit(size int64) HandlerOption {
+	return func(h *Handler) {
+		h.sizeLimit = size
+	}
+}
+
+// WithRepoSizeLimit limits the size of the caches of each repository, the least recently used caches
+// of the repository are evicted when it is exceeded, 0 means no limit
+func WithRepoSizeLimit(size int64) HandlerOption {
+	return func(h *Handler) {
+		h.repoSizeLimit = size
+	}
+}
+
+// WithAdminToken serves the usage of the caches of all the repositories at `GET /_apis/artifactcache/stats`
+// to the requests with `Authorization: Bearer <token>`, the endpoint is not served without it
+func WithAdminToken(token string) HandlerOption {
+	return func(h *Handler) {
+		h.adminToken = token
+	}
+}
+
+// UsageStats is the number and the size of the complete caches
+type UsageStats struct {
+	Count int   `json:"count"`
+	Size  int64 `json:"size"`
+}
+
+func (s *UsageStats) add(cache *Cache) {
+	s.Count++
+	s.Size += cacheSize(cache)
+}
+
+// Stats is the usage of the storage of the caches
+type Stats struct {
+	UsageStats
+	SizeLimit     int64 `json:"sizeLimit"`
+	RepoSizeLimit int64 `json:"repoSizeLimit"`
+	// DiskSize is the size of all the files in the storage, including the uploads in progress
+	DiskSize int64                  `json:"diskSize"`
+	Repos    map[string]*UsageStats `json:"repos"`
+}
+
+// Stats returns the usage of the storage of the caches in total and by repository
+func (h *Handler) Stats() (*Stats, error) {
+	db, err := h.openDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var caches []*Cache
+	if err := db.Find(&caches, bolthold.Where("Complete").Eq(true)); err != nil {
+		return nil, err
+	}
+	stats := &Stats{
+		SizeLimit:     h.sizeLimit,
+		RepoSizeLimit: h.repoSizeLimit,
+		Repos:         map[string]*UsageStats{},
+	}
+	for _, cache := range caches {
+		stats.add(cache)
+		repo, ok := stats.Repos[cache.Repo]
+		if !ok {
+			repo = &UsageStats{}
+			stats.Repos[cache.Repo] = repo
+		}
+		repo.add(cache)
+	}
+	if stats.DiskSize, err = h.storage.Usage(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GET /_apis/artifactcache/stats
+func (h *Handler) stats(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || h.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+		h.responseJSON(w, r, 401, fmt.Errorf("bad credentials"))
+		return
+	}
+	stats, err := h.Stats()
+	if err != nil {
+		h.responseJSON(w, r, 500, err)
+		return
+	}
+	h.responseJSON(w, r, 200, stats)
+}
+
+// evictCache removes the least recently used caches until the size of the caches of each repository
+// and the total size are in the limits.
+// The caches which are being uploaded are counted with the size they have been reserved with,
+// but they are not removed.
+func (h *Handler) evictCache() {
+	if h.sizeLimit <= 0 && h.repoSizeLimit <= 0 {
+		return
+	}
+	if !h.evicting.CompareAndSwap(false, true) {
+		return
+	}
+	defer h.evicting.Store(false)
+
+	db, err := h.openDB()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+
+	var caches []*Cache
+	if err := db.Find(&caches, (&bolthold.Query{}).SortBy("UsedAt", "ID")); err != nil {
+		h.logger.Warnf("find caches: %v", err)
+		return
+	}
+
+	var total int64
+	repos := map[string]int64{}
+	for _, cache := range caches {
+		total += cacheSize(cache)
+		repos[cache.Repo] += cacheSize(cache)
+	}
+
+	for _, cache := range caches {
+		if !cache.Complete {
+			continue
+		}
+		overRepo := h.repoSizeLimit > 0 && repos[cache.Repo] > h.repoSizeLimit
+		overTotal := h.sizeLimit > 0 && total > h.sizeLimit
+		if !overRepo && !overTotal {
+			continue
+		}
+		h.storage.Remove(cache.ID)
+		if err := db.Delete(cache.ID, cache); err != nil {
+			h.logger.Warnf("delete cache: %v", err)
+			continue
+		}
+		total -= cacheSize(cache)
+		repos[cache.Repo] -= cacheSize(cache)
+		h.logger.Infof("evicted cache: %+v", cache)
+	}
+}
+
+// checkCacheSize returns an error if the cache can never fit in the limits, 0 means no limit
+func (h *Handler) checkCacheSize(size int64) error {
+	for _, limit := range []int64{h.repoSizeLimit, h.sizeLimit} {
+		if limit > 0 && size > limit {
+			return fmt.Errorf("cache size of %d bytes is over the limit of %d bytes", size, limit)
+		}
+	}
+	return nil
+}
+
+// cacheSize returns the size of the cache, which is unknown before it is complete if the request doesn't specify it
+func cacheSize(cache *Cache) int64 {
+	if cache.Size < 0 {
+		return 0
+	}
+	return cache.Size
+}
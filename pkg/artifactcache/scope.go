@@ -0,0 +1,104 @@
+package artifactcache
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nektos/act/pkg/common"
+)
+
+// the headers which a trusted proxy in front of the handler can set to scope the requests,
+// they are ignored unless the handler is started with WithTrustedScopeHeaders
+const (
+	headerRepo        = "X-Actions-Cache-Repo"
+	headerRef         = "X-Actions-Cache-Ref"
+	headerRestoreRefs = "X-Actions-Cache-Restore-Refs"
+)
+
+// archiveLocationExpiry is how long the archive location of a cache found in the scope of a request can be downloaded
+const archiveLocationExpiry = 6 * time.Hour
+
+// WithTrustedScopeHeaders scopes the requests with the headers X-Actions-Cache-Repo, X-Actions-Cache-Ref
+// and X-Actions-Cache-Restore-Refs, which take precedence over the token.
+// It must only be used when the handler is reached through a proxy which sets the headers of every request,
+// since any job could send them to save caches to other refs.
+func WithTrustedScopeHeaders() HandlerOption {
+	return func(h *Handler) {
+		h.trustScopeHeaders = true
+	}
+}
+
+// getScope returns the scope of the request, which is read from the headers of a trusted proxy first and then
+// from the `ac` claim of its token. A token which can not be verified is an error.
+// The requests without a scope share the caches which have been saved without a scope.
+func (h *Handler) getScope(r *http.Request) (*common.CacheScope, error) {
+	if h.trustScopeHeaders && r.Header.Get(headerRepo) != "" {
+		scope := &common.CacheScope{
+			Repository: r.Header.Get(headerRepo),
+			Ref:        r.Header.Get(headerRef),
+		}
+		for _, ref := range strings.Split(r.Header.Get(headerRestoreRefs), ",") {
+			if ref = strings.TrimSpace(ref); ref != "" {
+				scope.RestoreRefs = append(scope.RestoreRefs, ref)
+			}
+		}
+		return scope, nil
+	}
+
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		token, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok {
+			return nil, fmt.Errorf("unsupported authorization scheme")
+		}
+		claims, err := common.ParseAuthorizationToken(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token: %w", err)
+		}
+		if claims.CacheScope != nil {
+			return claims.CacheScope, nil
+		}
+	}
+	return &common.CacheScope{}, nil
+}
+
+// inScope returns true if the cache has been saved with the scope
+func inScope(cache *Cache, scope *common.CacheScope) bool {
+	return cache.Repo == scope.Repository && cache.Ref == scope.Ref
+}
+
+// archiveLocation returns the url which downloads the cache, it is signed since the ids of the caches
+// can be guessed and the caches of other scopes must not be downloaded
+func (h *Handler) archiveLocation(id uint64) string {
+	expires := strconv.FormatInt(time.Now().Add(archiveLocationExpiry).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("sig", h.sign(id, expires))
+	return fmt.Sprintf("%s%s/artifacts/%d?%s", h.ExternalURL(), urlBase, id, query.Encode())
+}
+
+func (h *Handler) verifyArchiveLocation(r *http.Request, id uint64) error {
+	query := r.URL.Query()
+	expires := query.Get("expires")
+	if !hmac.Equal([]byte(query.Get("sig")), []byte(h.sign(id, expires))) {
+		return errors.New("invalid signature")
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().After(time.Unix(unix, 0)) {
+		return errors.New("the url has expired")
+	}
+	return nil
+}
+
+func (h *Handler) sign(id uint64, expires string) string {
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(fmt.Sprintf("%d\n%s", id, expires)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -3,6 +3,7 @@ package artifactcache
 import (
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -102,6 +103,26 @@ func (s *Storage) Remove(id uint64) {
 	_ = os.RemoveAll(s.tempDir(id))
 }
 
+// Usage returns the size of all the files in the storage
+func (s *Storage) Usage() (int64, error) {
+	var size int64
+	err := filepath.WalkDir(s.rootDir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
 func (s *Storage) filename(id uint64) string {
 	return filepath.Join(s.rootDir, fmt.Sprintf("%02x", id%0xff), fmt.Sprint(id))
 }
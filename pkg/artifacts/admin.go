@@ -0,0 +1,241 @@
+package artifacts
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/nektos/act/pkg/common"
+)
+
+type ArtifactMetadataResponse struct {
+	Count int                 `json:"count"`
+	Value []*ArtifactMetadata `json:"value"`
+}
+
+// PublicArtifact is an artifact of the REST API of GitHub
+type PublicArtifact struct {
+	ID                 int64  `json:"id"`
+	Name               string `json:"name"`
+	SizeInBytes        int64  `json:"size_in_bytes"`
+	ArchiveDownloadURL string `json:"archive_download_url"`
+	Expired            bool   `json:"expired"`
+	CreatedAt          string `json:"created_at"`
+	ExpiresAt          string `json:"expires_at,omitempty"`
+}
+
+type PublicArtifactsResponse struct {
+	TotalCount int               `json:"total_count"`
+	Artifacts  []*PublicArtifact `json:"artifacts"`
+}
+
+// admin manages the artifacts of all the runs, they are filtered with the query parameters `runId` and `name`.
+// The endpoints are not reachable without the token, which is sent as `Authorization: Bearer <token>`
+// or `Authorization: token <token>`.
+func admin(router *httprouter.Router, s *artifactV4Server, token string) {
+	router.GET("/_apis/artifacts/admin/artifacts", requireToken(token, func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		query := req.URL.Query()
+		list, err := s.list(query.Get("runId"), query.Get("name"))
+		if err != nil {
+			panic(err)
+		}
+		writeArtifactMetadata(w, list)
+	}))
+
+	router.DELETE("/_apis/artifacts/admin/artifacts", requireToken(token, func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		query := req.URL.Query()
+		runID, name := query.Get("runId"), query.Get("name")
+		if runID == "" && name == "" {
+			http.Error(w, "runId or name is required", http.StatusBadRequest)
+			return
+		}
+		list, err := s.list(runID, name)
+		if err != nil {
+			panic(err)
+		}
+		for _, meta := range list {
+			if err := s.remove(meta.RunID, meta.Name); err != nil {
+				panic(err)
+			}
+		}
+		writeArtifactMetadata(w, list)
+	}))
+}
+
+// publicAPI serves the REST API of GitHub which download-artifact uses with `run-id` to download the artifacts of other runs.
+// The requests are authorized with the ACTIONS_RUNTIME_TOKEN of a job as `github-token`, which can only read the artifacts
+// of the repository of the job.
+// The runner sets GITHUB_API_URL to the API of the instance, so the step of download-artifact has to set it to the server:
+//
+//	steps:
+//	  - uses: actions/download-artifact@v4
+//	    env:
+//	      GITHUB_API_URL: http://<artifact-server-addr>:<artifact-server-port>
+//	    with:
+//	      run-id: ${{ inputs.run-id }}
+//	      github-token: ${{ env.ACTIONS_RUNTIME_TOKEN }}
+func publicAPI(router *httprouter.Router, s *artifactV4Server) {
+	router.GET("/repos/:owner/:repo/actions/runs/:runId/artifacts", requireRuntimeToken(s.listPublicArtifacts))
+	router.GET("/repos/:owner/:repo/actions/artifacts/:artifactId/:format", requireRuntimeToken(s.downloadPublicArtifact))
+}
+
+// requireToken only calls the handle if the request has the token
+func requireToken(token string, handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		sent, ok := authorizationToken(req)
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			http.Error(w, "Bad credentials", http.StatusUnauthorized)
+			return
+		}
+		handle(w, req, params)
+	}
+}
+
+// requireRuntimeToken only calls the handle if the request has a token created by common.CreateAuthorizationToken,
+// a token with the repository of a job only reads the artifacts of the repository
+func requireRuntimeToken(handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		sent, ok := authorizationToken(req)
+		if !ok {
+			http.Error(w, "Bad credentials", http.StatusUnauthorized)
+			return
+		}
+		claims, err := common.ParseAuthorizationToken(sent)
+		if err != nil {
+			http.Error(w, "Bad credentials", http.StatusUnauthorized)
+			return
+		}
+		repo := params.ByName("owner") + "/" + params.ByName("repo")
+		if claims.CacheScope != nil && claims.CacheScope.Repository != "" && !strings.EqualFold(claims.CacheScope.Repository, repo) {
+			// like GitHub, the repositories which can not be accessed are not found
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		handle(w, req, params)
+	}
+}
+
+// authorizationToken returns the token of `Authorization: Bearer <token>` or `Authorization: token <token>`
+func authorizationToken(req *http.Request) (string, bool) {
+	auth := req.Header.Get("Authorization")
+	for _, scheme := range []string{"Bearer ", "token "} {
+		if token, ok := strings.CutPrefix(auth, scheme); ok && token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// listPublicArtifacts lists the finalized artifacts of a run like `GET /repos/{owner}/{repo}/actions/runs/{run_id}/artifacts`,
+// they are filtered with the query parameter `name` and paged with `per_page` and `page`
+func (s *artifactV4Server) listPublicArtifacts(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	query := req.URL.Query()
+	runID := params.ByName("runId")
+	list, err := s.list(runID, query.Get("name"))
+	if err != nil {
+		panic(err)
+	}
+
+	artifacts := make([]*PublicArtifact, 0, len(list))
+	for _, meta := range list {
+		if meta.Pending {
+			continue
+		}
+		id := int64(meta.id())
+		artifact := &PublicArtifact{
+			ID:                 id,
+			Name:               meta.Name,
+			SizeInBytes:        meta.Size,
+			ArchiveDownloadURL: fmt.Sprintf("http://%s/repos/%s/%s/actions/artifacts/%d/zip", req.Host, params.ByName("owner"), params.ByName("repo"), id),
+			CreatedAt:          meta.CreatedAt.Format(time.RFC3339),
+		}
+		if meta.ExpiresAt != nil {
+			artifact.ExpiresAt = meta.ExpiresAt.Format(time.RFC3339)
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	sort.SliceStable(artifacts, func(i, j int) bool {
+		return artifacts[i].CreatedAt > artifacts[j].CreatedAt
+	})
+
+	resp := PublicArtifactsResponse{TotalCount: len(artifacts), Artifacts: artifacts}
+	perPage, page := queryInt(query.Get("per_page"), 30), queryInt(query.Get("page"), 1)
+	if perPage > 100 {
+		perPage = 100
+	}
+	start := (page - 1) * perPage
+	if start > len(artifacts) {
+		start = len(artifacts)
+	}
+	end := start + perPage
+	if end > len(artifacts) {
+		end = len(artifacts)
+	}
+	resp.Artifacts = artifacts[start:end]
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+// downloadPublicArtifact redirects to the zip of an artifact like `GET /repos/{owner}/{repo}/actions/artifacts/{artifact_id}/zip`
+func (s *artifactV4Server) downloadPublicArtifact(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	if params.ByName("format") != "zip" {
+		http.Error(w, "only the zip format is supported", http.StatusNotFound)
+		return
+	}
+	id, err := strconv.ParseInt(params.ByName("artifactId"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid artifact id", http.StatusNotFound)
+		return
+	}
+	list, err := s.list("", "")
+	if err != nil {
+		panic(err)
+	}
+	var found []*ArtifactMetadata
+	for _, meta := range list {
+		if !meta.Pending && int64(meta.id()) == id {
+			found = append(found, meta)
+		}
+	}
+	switch len(found) {
+	case 0:
+		http.Error(w, fmt.Sprintf("artifact %d not found", id), http.StatusNotFound)
+	case 1:
+		http.Redirect(w, req, s.signedURL(req.Host, "download", found[0].RunID, found[0].Name), http.StatusFound)
+	default:
+		// the ids of the artifacts stored without one are hashes, another artifact must not be downloaded instead
+		http.Error(w, fmt.Sprintf("artifact id %d is ambiguous", id), http.StatusConflict)
+	}
+}
+
+func queryInt(value string, defaultValue int) int {
+	if i, err := strconv.Atoi(value); err == nil && i > 0 {
+		return i
+	}
+	return defaultValue
+}
+
+func writeArtifactMetadata(w http.ResponseWriter, list []*ArtifactMetadata) {
+	json, err := json.Marshal(ArtifactMetadataResponse{
+		Count: len(list),
+		Value: list,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = w.Write(json)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,186 @@
+package artifacts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nektos/act/pkg/common"
+)
+
+func TestAdminArtifacts(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestStore(t, 0, now)
+	writeArtifact(t, s, "1", "dist", 0, "dist")
+	writeArtifact(t, s, "1", "logs", 1, "logs")
+	writeArtifact(t, s, "2", "dist", 0, "dist")
+
+	router := httprouter.New()
+	admin(router, artifactsV4(router, s, []byte("key")), "admin")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	request := func(method, query, token string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+"/_apis/artifacts/admin/artifacts"+query, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return res
+	}
+	list := func(query string) []string {
+		res := request(http.MethodGet, query, "admin")
+		defer res.Body.Close()
+		resp := ArtifactMetadataResponse{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+		var ret []string
+		for _, meta := range resp.Value {
+			ret = append(ret, meta.RunID+"/"+meta.Name)
+		}
+		return ret
+	}
+	remove := func(query, token string) int {
+		res := request(http.MethodDelete, query, token)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	assert.Equal([]string{"1/dist", "1/logs", "2/dist"}, list(""))
+	assert.Equal([]string{"1/dist", "2/dist"}, list("?name=dist"))
+	assert.Equal([]string{"2/dist"}, list("?runId=2"))
+
+	for _, token := range []string{"", "other"} {
+		res := request(http.MethodGet, "", token)
+		res.Body.Close()
+		assert.Equal(http.StatusUnauthorized, res.StatusCode)
+		assert.Equal(http.StatusUnauthorized, remove("?name=dist", token))
+	}
+
+	assert.Equal(http.StatusBadRequest, remove("", "admin"))
+	assert.Equal(http.StatusOK, remove("?name=dist", "admin"))
+	assert.Equal([]string{"1/logs"}, list(""))
+}
+
+func TestPublicArtifacts(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestStore(t, 0, now)
+	writeArtifact(t, s, "1", "dist", 0, "dist")
+	writeArtifact(t, s, "1", "logs", 1, "logs")
+	writeArtifact(t, s, "2", "dist", 0, "other")
+
+	router := httprouter.New()
+	v4 := artifactsV4(router, s, []byte("key"))
+	admin(router, v4, "admin")
+	publicAPI(router, v4)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	token, err := common.CreateAuthorizationToken("3", "build", &common.CacheScope{Repository: "owner/repo", Ref: "refs/heads/main"})
+	require.NoError(t, err)
+	otherRepoToken, err := common.CreateAuthorizationToken("3", "build", &common.CacheScope{Repository: "owner/other", Ref: "refs/heads/main"})
+	require.NoError(t, err)
+
+	get := func(path, token string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "token "+token)
+		}
+		// download-artifact follows the redirect itself
+		res, err := http.DefaultTransport.RoundTrip(req)
+		require.NoError(t, err)
+		return res
+	}
+
+	// the admin token can not read the artifacts, since it's not passed to the jobs
+	for _, invalid := range []string{"", "admin", token + "x"} {
+		res := get("/repos/owner/repo/actions/runs/1/artifacts", invalid)
+		res.Body.Close()
+		assert.Equal(http.StatusUnauthorized, res.StatusCode)
+	}
+	res := get("/repos/owner/repo/actions/runs/1/artifacts", otherRepoToken)
+	res.Body.Close()
+	assert.Equal(http.StatusNotFound, res.StatusCode)
+
+	res = get("/repos/owner/repo/actions/runs/1/artifacts?name=dist", token)
+	listed := PublicArtifactsResponse{}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&listed))
+	res.Body.Close()
+	assert.Equal(1, listed.TotalCount)
+	require.Len(t, listed.Artifacts, 1)
+	artifact := listed.Artifacts[0]
+	assert.Equal("dist", artifact.Name)
+	meta, err := s.readMetadata("1", "dist")
+	require.NoError(t, err)
+	assert.Equal(meta.ID, artifact.ID)
+	assert.Equal(int64(4), artifact.SizeInBytes)
+
+	res = get("/repos/owner/repo/actions/runs/1/artifacts?per_page=1&page=2", token)
+	listed = PublicArtifactsResponse{}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&listed))
+	res.Body.Close()
+	assert.Equal(2, listed.TotalCount)
+	assert.Len(listed.Artifacts, 1)
+
+	res = get(fmt.Sprintf("/repos/owner/repo/actions/artifacts/%d/zip", artifact.ID), token)
+	res.Body.Close()
+	require.Equal(t, http.StatusFound, res.StatusCode)
+	res, err = http.Get(res.Header.Get("Location"))
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(map[string]string{"file.txt": "dist"}, readZip(t, body))
+
+	// the artifacts with the same id are not downloaded, since any of them could be the wrong one
+	other, err := s.readMetadata("2", "dist")
+	require.NoError(t, err)
+	other.ID = meta.ID
+	require.NoError(t, s.writeMetadata(other))
+	res = get(fmt.Sprintf("/repos/owner/repo/actions/artifacts/%d/zip", artifact.ID), token)
+	res.Body.Close()
+	assert.Equal(http.StatusConflict, res.StatusCode)
+
+	// expired artifacts can not be downloaded
+	s.now = func() time.Time { return now.AddDate(0, 0, 1) }
+	logs, err := s.readMetadata("1", "logs")
+	require.NoError(t, err)
+	res = get(fmt.Sprintf("/repos/owner/repo/actions/artifacts/%d/zip", logs.ID), token)
+	res.Body.Close()
+	assert.Equal(http.StatusNotFound, res.StatusCode)
+}
+
+func TestLegacyUploadMetadata(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestStore(t, 0, now)
+
+	router := httprouter.New()
+	uploads(router, s.baseDir, s.fsys, s)
+
+	req, _ := http.NewRequest("POST", "http://localhost/_apis/pipelines/workflows/1/artifacts", bytes.NewBufferString(`{"Type":"actions_storage","Name":"dist","RetentionDays":3}`))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	req, _ = http.NewRequest("PUT", "http://localhost/upload/1?itemPath=dist/file.txt", bytes.NewBufferString("content"))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	req, _ = http.NewRequest("PATCH", "http://localhost/_apis/pipelines/workflows/1/artifacts?artifactName=dist", bytes.NewBufferString(`{"Size":7}`))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	meta, err := s.readMetadata("1", "dist")
+	require.NoError(t, err)
+	assert.Equal(int64(7), meta.Size)
+	assert.Equal(3, meta.RetentionDays)
+	assert.Equal(now.AddDate(0, 0, 3), *meta.ExpiresAt)
+}
@@ -0,0 +1,545 @@
+package artifacts
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/nektos/act/pkg/common"
+)
+
+// the Twirp service which is used by actions/upload-artifact@v4 and actions/download-artifact@v4,
+// only the JSON encoding of Twirp is supported
+const artifactServiceV4 = "/twirp/github.actions.results.api.v1.ArtifactService/"
+
+const (
+	artifactV4File      = "artifact.zip"
+	artifactV4BlocksDir = ".blocks"
+	signedURLExpiry     = time.Hour
+)
+
+// ReadWriteFS is the file system of the artifacts which are read and written
+type ReadWriteFS interface {
+	fs.FS
+	WriteFS
+}
+
+type remover interface {
+	RemoveAll(name string) error
+}
+
+// jsonInt64 is an int64 in the JSON mapping of protobuf, which is a string but numbers are accepted as well
+type jsonInt64 int64
+
+func (i jsonInt64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(i), 10))
+}
+
+func (i *jsonInt64) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*i = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = jsonInt64(v)
+	return nil
+}
+
+// twirpRequest is a request of the Twirp service, which is made by a job of a run
+type twirpRequest interface {
+	backendIDs() (runID, jobID string)
+}
+
+type artifactV4Request struct {
+	WorkflowRunBackendID    string `json:"workflowRunBackendId"`
+	WorkflowJobRunBackendID string `json:"workflowJobRunBackendId"`
+	Name                    string `json:"name"`
+}
+
+func (r *artifactV4Request) backendIDs() (string, string) {
+	return r.WorkflowRunBackendID, r.WorkflowJobRunBackendID
+}
+
+type CreateArtifactRequest struct {
+	artifactV4Request
+	Version   int        `json:"version"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type CreateArtifactResponse struct {
+	OK              bool   `json:"ok"`
+	SignedUploadURL string `json:"signedUploadUrl"`
+}
+
+type FinalizeArtifactRequest struct {
+	artifactV4Request
+	Size jsonInt64 `json:"size"`
+	Hash *string   `json:"hash"`
+}
+
+type FinalizeArtifactResponse struct {
+	OK         bool      `json:"ok"`
+	ArtifactID jsonInt64 `json:"artifactId"`
+}
+
+type ListArtifactsRequest struct {
+	WorkflowRunBackendID    string     `json:"workflowRunBackendId"`
+	WorkflowJobRunBackendID string     `json:"workflowJobRunBackendId"`
+	NameFilter              *string    `json:"nameFilter"`
+	IDFilter                *jsonInt64 `json:"idFilter"`
+}
+
+func (r *ListArtifactsRequest) backendIDs() (string, string) {
+	return r.WorkflowRunBackendID, r.WorkflowJobRunBackendID
+}
+
+type ListArtifactsResponse struct {
+	Artifacts []*ArtifactV4 `json:"artifacts"`
+}
+
+type ArtifactV4 struct {
+	WorkflowRunBackendID    string    `json:"workflowRunBackendId"`
+	WorkflowJobRunBackendID string    `json:"workflowJobRunBackendId"`
+	DatabaseID              jsonInt64 `json:"databaseId"`
+	Name                    string    `json:"name"`
+	Size                    jsonInt64 `json:"size"`
+	CreatedAt               string    `json:"createdAt,omitempty"`
+}
+
+type GetSignedArtifactURLRequest struct {
+	artifactV4Request
+}
+
+type GetSignedArtifactURLResponse struct {
+	SignedURL string `json:"signedUrl"`
+}
+
+type DeleteArtifactRequest struct {
+	artifactV4Request
+}
+
+type DeleteArtifactResponse struct {
+	OK         bool      `json:"ok"`
+	ArtifactID jsonInt64 `json:"artifactId"`
+}
+
+// twirpError is an error response of Twirp
+type twirpError struct {
+	Code   string `json:"code"`
+	Msg    string `json:"msg"`
+	status int
+}
+
+func (e *twirpError) Error() string {
+	return e.Code + ": " + e.Msg
+}
+
+func newTwirpError(status int, code string, format string, args ...interface{}) *twirpError {
+	return &twirpError{Code: code, Msg: fmt.Sprintf(format, args...), status: status}
+}
+
+type artifactV4Server struct {
+	*store
+	key []byte
+}
+
+// blockList is the body of `Put Block List` of Azure Blob Storage, the blocks are committed in order
+type blockList struct {
+	Blocks []struct {
+		ID string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+func artifactsV4(router *httprouter.Router, artifacts *store, key []byte) *artifactV4Server {
+	s := &artifactV4Server{store: artifacts, key: key}
+
+	router.POST(artifactServiceV4+":method", s.handleTwirp)
+	router.PUT("/_apis/artifacts/v4/upload", s.upload)
+	router.GET("/_apis/artifacts/v4/download", s.download)
+	return s
+}
+
+func (s *artifactV4Server) handleTwirp(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		writeTwirpError(w, newTwirpError(http.StatusBadRequest, "malformed", "only the JSON encoding is supported"))
+		return
+	}
+
+	var resp interface{}
+	var err error
+	switch params.ByName("method") {
+	case "CreateArtifact":
+		body := &CreateArtifactRequest{}
+		if err = readTwirpRequest(req, body); err == nil {
+			resp, err = s.createArtifact(req, body)
+		}
+	case "FinalizeArtifact":
+		body := &FinalizeArtifactRequest{}
+		if err = readTwirpRequest(req, body); err == nil {
+			resp, err = s.finalizeArtifact(body)
+		}
+	case "ListArtifacts":
+		body := &ListArtifactsRequest{}
+		if err = readTwirpRequest(req, body); err == nil {
+			resp, err = s.listArtifacts(body)
+		}
+	case "GetSignedArtifactURL":
+		body := &GetSignedArtifactURLRequest{}
+		if err = readTwirpRequest(req, body); err == nil {
+			resp, err = s.getSignedArtifactURL(req, body)
+		}
+	case "DeleteArtifact":
+		body := &DeleteArtifactRequest{}
+		if err = readTwirpRequest(req, body); err == nil {
+			resp, err = s.deleteArtifact(body)
+		}
+	default:
+		err = newTwirpError(http.StatusNotFound, "bad_route", "unknown method %q", params.ByName("method"))
+	}
+	if err != nil {
+		writeTwirpError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+// readTwirpRequest decodes the request and verifies its bearer token, which is created by common.CreateAuthorizationToken.
+// A job may only access the artifacts of its run, like on GitHub the token has to have the scope
+// `Actions.Results:<run>:<job>` of the run and the job of the request.
+func readTwirpRequest(req *http.Request, body twirpRequest) error {
+	if err := json.NewDecoder(req.Body).Decode(body); err != nil {
+		return newTwirpError(http.StatusBadRequest, "malformed", "invalid request: %v", err)
+	}
+
+	token := req.Header.Get("Authorization")
+	if !strings.HasPrefix(token, "Bearer ") {
+		return newTwirpError(http.StatusUnauthorized, "unauthenticated", "the bearer token is required")
+	}
+	claims, err := common.ParseAuthorizationToken(strings.TrimPrefix(token, "Bearer "))
+	if err != nil {
+		return newTwirpError(http.StatusUnauthorized, "unauthenticated", "invalid token: %v", err)
+	}
+	runID, jobID := body.backendIDs()
+	scope := fmt.Sprintf("Actions.Results:%s:%s", runID, jobID)
+	for _, s := range claims.Scopes {
+		if s == scope {
+			return nil
+		}
+	}
+	return newTwirpError(http.StatusForbidden, "permission_denied", "the token does not allow to access the artifacts of job %q of run %q", jobID, runID)
+}
+
+func writeTwirpError(w http.ResponseWriter, err error) {
+	var twerr *twirpError
+	if !errors.As(err, &twerr) {
+		twerr = newTwirpError(http.StatusInternalServerError, "internal", "%v", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(twerr.status)
+	_ = json.NewEncoder(w).Encode(twerr)
+}
+
+func (s *artifactV4Server) createArtifact(req *http.Request, body *CreateArtifactRequest) (*CreateArtifactResponse, error) {
+	if err := validateArtifactV4Request(&body.artifactV4Request); err != nil {
+		return nil, err
+	}
+	// like on GitHub, an artifact is not replaced unless it is deleted first, which upload-artifact does with `overwrite`
+	if meta, err := s.readMetadata(body.WorkflowRunBackendID, body.Name); err == nil && !meta.Pending && !meta.Expired(s.now()) {
+		return nil, newTwirpError(http.StatusConflict, "already_exists", "artifact %q already exists", body.Name)
+	}
+	file, err := s.fsys.OpenWritable(s.artifactPath(body.WorkflowRunBackendID, body.Name, artifactV4File))
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	retentionDays := 0
+	if body.ExpiresAt != nil {
+		retentionDays = int(math.Ceil(body.ExpiresAt.Sub(s.now()).Hours() / 24))
+	}
+	meta := s.newMetadata(body.WorkflowRunBackendID, body.WorkflowJobRunBackendID, body.Name, retentionDays)
+	meta.Pending = true
+	if err := s.writeMetadata(meta); err != nil {
+		return nil, err
+	}
+	return &CreateArtifactResponse{
+		OK:              true,
+		SignedUploadURL: s.signedURL(req.Host, "upload", body.WorkflowRunBackendID, body.Name),
+	}, nil
+}
+
+func (s *artifactV4Server) finalizeArtifact(body *FinalizeArtifactRequest) (*FinalizeArtifactResponse, error) {
+	if err := validateArtifactV4Request(&body.artifactV4Request); err != nil {
+		return nil, err
+	}
+	file, err := s.fsys.Open(s.artifactPath(body.WorkflowRunBackendID, body.Name, artifactV4File))
+	if err != nil {
+		return nil, newTwirpError(http.StatusNotFound, "not_found", "artifact %q has not been created", body.Name)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return nil, err
+	}
+	if body.Size != 0 && int64(body.Size) != size {
+		return nil, newTwirpError(http.StatusBadRequest, "invalid_argument", "artifact %q has %d bytes, but %d bytes are expected", body.Name, size, body.Size)
+	}
+	if body.Hash != nil && *body.Hash != "" {
+		if hash := "sha256:" + hex.EncodeToString(h.Sum(nil)); hash != *body.Hash {
+			return nil, newTwirpError(http.StatusBadRequest, "invalid_argument", "artifact %q has hash %s, but %s is expected", body.Name, hash, *body.Hash)
+		}
+	}
+	meta, err := s.updateMetadata(body.WorkflowRunBackendID, body.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &FinalizeArtifactResponse{OK: true, ArtifactID: meta.id()}, nil
+}
+
+func (s *artifactV4Server) listArtifacts(body *ListArtifactsRequest) (*ListArtifactsResponse, error) {
+	if body.WorkflowRunBackendID == "" {
+		return nil, newTwirpError(http.StatusBadRequest, "invalid_argument", "the workflow run is required")
+	}
+	name := ""
+	if body.NameFilter != nil {
+		name = *body.NameFilter
+	}
+	metas, err := s.list(body.WorkflowRunBackendID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ListArtifactsResponse{Artifacts: []*ArtifactV4{}}
+	for _, meta := range metas {
+		id := meta.id()
+		// like on GitHub, the artifacts which have not been finalized are not listed
+		if meta.Pending || body.IDFilter != nil && *body.IDFilter != id {
+			continue
+		}
+		resp.Artifacts = append(resp.Artifacts, &ArtifactV4{
+			WorkflowRunBackendID:    meta.RunID,
+			WorkflowJobRunBackendID: meta.JobID,
+			DatabaseID:              id,
+			Name:                    meta.Name,
+			Size:                    jsonInt64(meta.Size),
+			CreatedAt:               meta.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return resp, nil
+}
+
+func (s *artifactV4Server) getSignedArtifactURL(req *http.Request, body *GetSignedArtifactURLRequest) (*GetSignedArtifactURLResponse, error) {
+	if err := validateArtifactV4Request(&body.artifactV4Request); err != nil {
+		return nil, err
+	}
+	if meta, err := s.readMetadata(body.WorkflowRunBackendID, body.Name); err != nil || meta.Expired(s.now()) || meta.Pending {
+		return nil, newTwirpError(http.StatusNotFound, "not_found", "artifact %q not found", body.Name)
+	}
+	return &GetSignedArtifactURLResponse{
+		SignedURL: s.signedURL(req.Host, "download", body.WorkflowRunBackendID, body.Name),
+	}, nil
+}
+
+func (s *artifactV4Server) deleteArtifact(body *DeleteArtifactRequest) (*DeleteArtifactResponse, error) {
+	if err := validateArtifactV4Request(&body.artifactV4Request); err != nil {
+		return nil, err
+	}
+	meta, err := s.readMetadata(body.WorkflowRunBackendID, body.Name)
+	if err != nil || meta.Expired(s.now()) {
+		return nil, newTwirpError(http.StatusNotFound, "not_found", "artifact %q not found", body.Name)
+	}
+	if err := s.remove(body.WorkflowRunBackendID, body.Name); err != nil {
+		return nil, err
+	}
+	return &DeleteArtifactResponse{OK: true, ArtifactID: meta.id()}, nil
+}
+
+// upload receives the blob of an artifact like Azure Blob Storage, the blob is put at once or in blocks
+// which are committed with a block list
+func (s *artifactV4Server) upload(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	runID, name, err := s.verifySignedURL(req, "upload")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	// the upload url is still valid after the artifact has been finalized, but the artifact must not change anymore
+	if meta, err := s.readMetadata(runID, name); err != nil {
+		http.Error(w, fmt.Sprintf("artifact %q not found", name), http.StatusNotFound)
+		return
+	} else if !meta.Pending {
+		http.Error(w, fmt.Sprintf("artifact %q has been finalized", name), http.StatusConflict)
+		return
+	}
+	query := req.URL.Query()
+	artifactFile := s.artifactPath(runID, name, artifactV4File)
+
+	switch query.Get("comp") {
+	case "block":
+		blockID := query.Get("blockid")
+		if blockID == "" {
+			http.Error(w, "missing blockid", http.StatusBadRequest)
+			return
+		}
+		err = s.writeFile(s.blockPath(runID, name, blockID), req.Body, false)
+	case "appendBlock":
+		err = s.writeFile(artifactFile, req.Body, true)
+	case "blocklist":
+		err = s.commitBlocks(runID, name, req.Body)
+	default:
+		err = s.writeFile(artifactFile, req.Body, false)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *artifactV4Server) writeFile(name string, body io.Reader, appendable bool) error {
+	open := s.fsys.OpenWritable
+	if appendable {
+		open = s.fsys.OpenAppendable
+	}
+	file, err := open(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (s *artifactV4Server) commitBlocks(runID, name string, body io.Reader) error {
+	list := &blockList{}
+	if err := xml.NewDecoder(body).Decode(list); err != nil {
+		return fmt.Errorf("invalid block list: %w", err)
+	}
+
+	file, err := s.fsys.OpenWritable(s.artifactPath(runID, name, artifactV4File))
+	if err != nil {
+		return err
+	}
+	for _, block := range list.Blocks {
+		if err := s.copyFile(file, s.blockPath(runID, name, block.ID)); err != nil {
+			file.Close()
+			return fmt.Errorf("block %q: %w", block.ID, err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if r, ok := s.fsys.(remover); ok {
+		return r.RemoveAll(s.blocksDir(runID, name))
+	}
+	return nil
+}
+
+// download sends the artifact as zip, the artifacts uploaded with the legacy API are zipped on the fly
+func (s *artifactV4Server) download(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	runID, name, err := s.verifySignedURL(req, "download")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !s.exists(runID, name) {
+		http.Error(w, fmt.Sprintf("artifact %q not found", name), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	if err := s.writeArchive(w, runID, name); err != nil {
+		panic(err)
+	}
+}
+
+// exists returns true if the artifact has been created and has not expired
+func (s *artifactV4Server) exists(runID, name string) bool {
+	meta, err := s.readMetadata(runID, name)
+	return err == nil && !meta.Expired(s.now())
+}
+
+func (s *artifactV4Server) blocksDir(runID, name string) string {
+	return safeResolve(safeResolve(safeResolve(s.baseDir, artifactV4BlocksDir), runID), name)
+}
+
+func (s *artifactV4Server) blockPath(runID, name, blockID string) string {
+	return filepath.Join(s.blocksDir(runID, name), hex.EncodeToString([]byte(blockID)))
+}
+
+func (s *artifactV4Server) signedURL(host, action, runID, name string) string {
+	expires := strconv.FormatInt(time.Now().Add(signedURLExpiry).Unix(), 10)
+	query := url.Values{}
+	query.Set("runId", runID)
+	query.Set("name", name)
+	query.Set("expires", expires)
+	query.Set("sig", s.sign(action, runID, name, expires))
+	return fmt.Sprintf("http://%s/_apis/artifacts/v4/%s?%s", host, action, query.Encode())
+}
+
+func (s *artifactV4Server) verifySignedURL(req *http.Request, action string) (string, string, error) {
+	query := req.URL.Query()
+	runID, name, expires := query.Get("runId"), query.Get("name"), query.Get("expires")
+	if !hmac.Equal([]byte(query.Get("sig")), []byte(s.sign(action, runID, name, expires))) {
+		return "", "", errors.New("invalid signature")
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().After(time.Unix(unix, 0)) {
+		return "", "", errors.New("the url has expired")
+	}
+	return runID, name, nil
+}
+
+func (s *artifactV4Server) sign(action, runID, name, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strings.Join([]string{action, runID, name, expires}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func validateArtifactV4Request(req *artifactV4Request) error {
+	if req.WorkflowRunBackendID == "" || req.Name == "" {
+		return newTwirpError(http.StatusBadRequest, "invalid_argument", "the workflow run and the name of the artifact are required")
+	}
+	if strings.ContainsAny(req.Name, `\/":<>|*?`) || strings.HasPrefix(req.Name, ".") {
+		return newTwirpError(http.StatusBadRequest, "invalid_argument", "invalid name of artifact %q", req.Name)
+	}
+	return nil
+}
+
+// artifactID returns a stable id of an artifact of a run, which is stored without an id
+func artifactID(runID, name string) jsonInt64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(runID + "/" + name))
+	return jsonInt64(h.Sum32())
+}
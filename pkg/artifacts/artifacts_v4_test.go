@@ -0,0 +1,271 @@
+package artifacts
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nektos/act/pkg/common"
+)
+
+func newArtifactsV4Server(t *testing.T) (*httptest.Server, string) {
+	baseDir := t.TempDir()
+	router := httprouter.New()
+	artifactsV4(router, newStore(baseDir, readWriteFSImpl{}, 0), []byte("key"))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, baseDir
+}
+
+// callArtifactService calls the method with a token of the run and the job of the body
+func callArtifactService(t *testing.T, server *httptest.Server, method string, body map[string]interface{}, resp interface{}) int {
+	runID, _ := body["workflowRunBackendId"].(string)
+	jobID, _ := body["workflowJobRunBackendId"].(string)
+	token, err := common.CreateAuthorizationToken(runID, jobID, nil)
+	require.NoError(t, err)
+	return callArtifactServiceWithToken(t, server, method, token, body, resp)
+}
+
+func callArtifactServiceWithToken(t *testing.T, server *httptest.Server, method, token string, body, resp interface{}) int {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, server.URL+artifactServiceV4+method, bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.NoError(t, json.NewDecoder(res.Body).Decode(resp))
+	return res.StatusCode
+}
+
+func putBlob(t *testing.T, rawURL string, query string, body string) int {
+	req, err := http.NewRequest(http.MethodPut, rawURL+"&"+query, strings.NewReader(body))
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	return res.StatusCode
+}
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestArtifactsV4UploadDownload(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newArtifactsV4Server(t)
+
+	artifact := map[string]interface{}{"workflowRunBackendId": "1", "workflowJobRunBackendId": "build", "name": "dist"}
+
+	created := &CreateArtifactResponse{}
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "CreateArtifact", artifact, created))
+	assert.True(created.OK)
+
+	assert.Equal(http.StatusCreated, putBlob(t, created.SignedUploadURL, "comp=block&blockid=YQ%3D%3D", "hello "))
+	assert.Equal(http.StatusCreated, putBlob(t, created.SignedUploadURL, "comp=block&blockid=Yg%3D%3D", "world"))
+	blockList := `<?xml version="1.0" encoding="utf-8"?><BlockList><Latest>YQ==</Latest><Latest>Yg==</Latest></BlockList>`
+	assert.Equal(http.StatusCreated, putBlob(t, created.SignedUploadURL, "comp=blocklist", blockList))
+
+	sum := sha256.Sum256([]byte("hello world"))
+	finalize := map[string]interface{}{"workflowRunBackendId": "1", "workflowJobRunBackendId": "build", "name": "dist", "size": "11", "hash": "sha256:" + hex.EncodeToString(sum[:])}
+	finalized := &FinalizeArtifactResponse{}
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "FinalizeArtifact", finalize, finalized))
+	assert.True(finalized.OK)
+	assert.NotZero(finalized.ArtifactID)
+
+	// the artifact can not be changed once it has been finalized
+	assert.Equal(http.StatusConflict, putBlob(t, created.SignedUploadURL, "", "replaced"))
+	twerr := &twirpError{}
+	assert.Equal(http.StatusConflict, callArtifactService(t, server, "CreateArtifact", artifact, twerr))
+	assert.Equal("already_exists", twerr.Code)
+
+	finalize["hash"] = "sha256:0000"
+	twerr = &twirpError{}
+	assert.Equal(http.StatusBadRequest, callArtifactService(t, server, "FinalizeArtifact", finalize, twerr))
+	assert.Equal("invalid_argument", twerr.Code)
+
+	listed := &ListArtifactsResponse{}
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "ListArtifacts", map[string]interface{}{"workflowRunBackendId": "1", "nameFilter": "dist"}, listed))
+	if assert.Len(listed.Artifacts, 1) {
+		assert.Equal("dist", listed.Artifacts[0].Name)
+		assert.Equal(jsonInt64(11), listed.Artifacts[0].Size)
+		assert.Equal(finalized.ArtifactID, listed.Artifacts[0].DatabaseID)
+	}
+
+	signed := &GetSignedArtifactURLResponse{}
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "GetSignedArtifactURL", artifact, signed))
+	res, err := http.Get(signed.SignedURL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Equal("hello world", string(body))
+
+	deleted := &DeleteArtifactResponse{}
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "DeleteArtifact", artifact, deleted))
+	assert.True(deleted.OK)
+	assert.Equal(http.StatusNotFound, callArtifactService(t, server, "GetSignedArtifactURL", artifact, &twirpError{}))
+	assert.Equal(http.StatusNotFound, putBlob(t, created.SignedUploadURL, "", "replaced"))
+
+	// the artifact can be created again once it has been deleted
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "CreateArtifact", artifact, &CreateArtifactResponse{}))
+}
+
+func TestArtifactsV4DownloadLegacyArtifact(t *testing.T) {
+	assert := assert.New(t)
+	server, baseDir := newArtifactsV4Server(t)
+
+	dir := filepath.Join(baseDir, "1", "logs")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("plain"), 0o644))
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, _ = gw.Write([]byte("compressed"))
+	require.NoError(t, gw.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"+gzipExtension), gz.Bytes(), 0o644))
+
+	signed := &GetSignedArtifactURLResponse{}
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "GetSignedArtifactURL", map[string]interface{}{"workflowRunBackendId": "1", "name": "logs"}, signed))
+	res, err := http.Get(signed.SignedURL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(map[string]string{"a.txt": "plain", "sub/b.txt": "compressed"}, readZip(t, body))
+}
+
+func TestArtifactsV4SignedURL(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newArtifactsV4Server(t)
+
+	created := &CreateArtifactResponse{}
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "CreateArtifact", map[string]interface{}{"workflowRunBackendId": "1", "name": "dist"}, created))
+
+	for _, tampered := range []func(url.Values){
+		func(q url.Values) { q.Set("name", "other") },
+		func(q url.Values) { q.Set("runId", "2") },
+		func(q url.Values) { q.Set("sig", "00") },
+		func(q url.Values) { q.Set("expires", "1") },
+	} {
+		u, err := url.Parse(created.SignedUploadURL)
+		require.NoError(t, err)
+		q := u.Query()
+		tampered(q)
+		u.RawQuery = q.Encode()
+		assert.Equal(http.StatusUnauthorized, putBlob(t, u.String(), "comp=block&blockid=YQ%3D%3D", "data"))
+	}
+
+	// the upload url can not be used to download
+	res, err := http.Get(strings.Replace(created.SignedUploadURL, "/upload?", "/download?", 1))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestArtifactsV4InvalidRequest(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newArtifactsV4Server(t)
+
+	twerr := &twirpError{}
+	assert.Equal(http.StatusBadRequest, callArtifactService(t, server, "CreateArtifact", map[string]interface{}{"workflowRunBackendId": "1", "name": "../escape"}, twerr))
+	assert.Equal("invalid_argument", twerr.Code)
+
+	twerr = &twirpError{}
+	assert.Equal(http.StatusNotFound, callArtifactService(t, server, "Unknown", map[string]interface{}{}, twerr))
+	assert.Equal("bad_route", twerr.Code)
+
+	twerr = &twirpError{}
+	assert.Equal(http.StatusNotFound, callArtifactService(t, server, "FinalizeArtifact", map[string]interface{}{"workflowRunBackendId": "1", "name": "missing"}, twerr))
+	assert.Equal("not_found", twerr.Code)
+}
+
+func TestArtifactsV4Authorization(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newArtifactsV4Server(t)
+
+	artifact := map[string]interface{}{"workflowRunBackendId": "1", "workflowJobRunBackendId": "build", "name": "dist"}
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "CreateArtifact", artifact, &CreateArtifactResponse{}))
+
+	other, err := common.CreateAuthorizationToken("2", "build", nil)
+	require.NoError(t, err)
+	for _, method := range []string{"CreateArtifact", "FinalizeArtifact", "ListArtifacts", "GetSignedArtifactURL", "DeleteArtifact"} {
+		twerr := &twirpError{}
+		assert.Equal(http.StatusUnauthorized, callArtifactServiceWithToken(t, server, method, "", artifact, twerr), method)
+		assert.Equal("unauthenticated", twerr.Code)
+		assert.Equal(http.StatusUnauthorized, callArtifactServiceWithToken(t, server, method, "invalid", artifact, &twirpError{}), method)
+
+		// the token of another run can not access the artifacts of the run
+		twerr = &twirpError{}
+		assert.Equal(http.StatusForbidden, callArtifactServiceWithToken(t, server, method, other, artifact, twerr), method)
+		assert.Equal("permission_denied", twerr.Code)
+	}
+
+	// the artifact has not been finalized
+	listed := &ListArtifactsResponse{}
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "ListArtifacts", map[string]interface{}{"workflowRunBackendId": "1", "workflowJobRunBackendId": "build"}, listed))
+	assert.Empty(listed.Artifacts)
+	assert.Equal(http.StatusNotFound, callArtifactService(t, server, "GetSignedArtifactURL", artifact, &twirpError{}))
+}
+
+func TestArtifactsV4Retention(t *testing.T) {
+	assert := assert.New(t)
+	baseDir := t.TempDir()
+	artifacts := newStore(baseDir, readWriteFSImpl{}, 90)
+	router := httprouter.New()
+	artifactsV4(router, artifacts, []byte("key"))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	expiresAt := time.Now().Add(5 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "CreateArtifact", map[string]interface{}{"workflowRunBackendId": "1", "name": "short", "expiresAt": expiresAt}, &CreateArtifactResponse{}))
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "CreateArtifact", map[string]interface{}{"workflowRunBackendId": "1", "name": "default"}, &CreateArtifactResponse{}))
+	for _, name := range []string{"short", "default"} {
+		assert.Equal(http.StatusOK, callArtifactService(t, server, "FinalizeArtifact", map[string]interface{}{"workflowRunBackendId": "1", "name": name}, &FinalizeArtifactResponse{}))
+	}
+
+	meta, err := artifacts.readMetadata("1", "short")
+	require.NoError(t, err)
+	assert.Equal(5, meta.RetentionDays)
+	meta, err = artifacts.readMetadata("1", "default")
+	require.NoError(t, err)
+	assert.Equal(90, meta.RetentionDays)
+
+	artifacts.now = func() time.Time { return time.Now().AddDate(0, 0, 6) }
+	listed := &ListArtifactsResponse{}
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "ListArtifacts", map[string]interface{}{"workflowRunBackendId": "1"}, listed))
+	if assert.Len(listed.Artifacts, 1) {
+		assert.Equal("default", listed.Artifacts[0].Name)
+	}
+	assert.Equal(http.StatusNotFound, callArtifactService(t, server, "GetSignedArtifactURL", map[string]interface{}{"workflowRunBackendId": "1", "name": "short"}, &twirpError{}))
+}
@@ -0,0 +1,115 @@
+package artifacts
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage stores the files of the artifacts in a directory of the local disk
+type LocalStorage struct {
+	root string
+}
+
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{root: root}
+}
+
+func (s *LocalStorage) path(name string) string {
+	return safeResolve(s.root, filepath.FromSlash(name))
+}
+
+func (s *LocalStorage) Put(name string, r io.Reader) error {
+	return s.write(name, r, os.O_TRUNC)
+}
+
+func (s *LocalStorage) Append(name string, r io.Reader) error {
+	return s.write(name, r, os.O_APPEND)
+}
+
+func (s *LocalStorage) write(name string, r io.Reader, flag int) error {
+	p := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|flag, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (s *LocalStorage) Stat(name string) (*ObjectInfo, error) {
+	info, err := os.Stat(s.path(name))
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return &ObjectInfo{Name: name, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (s *LocalStorage) List(prefix string) ([]*ObjectInfo, error) {
+	// walk the directory of the prefix, the names in it are filtered by the prefix
+	dir := s.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = s.path(prefix[:i])
+	}
+
+	var ret []*ObjectInfo
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		ret = append(ret, &ObjectInfo{Name: name, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return ret, err
+}
+
+func (s *LocalStorage) Open(name string, offset, length int64) (io.ReadCloser, error) {
+	file, err := os.Open(s.path(name))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return file, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, nil
+}
+
+func (s *LocalStorage) Delete(name string) error {
+	return os.RemoveAll(s.path(name))
+}
@@ -0,0 +1,565 @@
+package artifacts
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// partsDir contains the parts which have been appended to the files
+const partsDir = ".parts/"
+
+// S3Config configures an S3 compatible object storage
+type S3Config struct {
+	// Endpoint is the url of the object storage, AWS S3 of the region is used if empty
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix is prepended to the names of the files
+	Prefix string
+	// PathStyle puts the bucket in the path instead of the host, which most S3 compatible storages require
+	PathStyle bool
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3Storage stores the files of the artifacts in an S3 compatible object storage, the requests are signed
+// with AWS Signature Version 4
+type S3Storage struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+	// sequence orders the parts appended at the same time
+	sequence uint64
+}
+
+func NewS3Storage(config *S3Config) (*S3Storage, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("the bucket of the S3 storage is required")
+	}
+	c := *config
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", c.Region)
+	}
+	if c.Prefix != "" && !strings.HasSuffix(c.Prefix, "/") {
+		c.Prefix += "/"
+	}
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint of the S3 storage '%s': %w", c.Endpoint, err)
+	}
+	return &S3Storage{config: c, endpoint: endpoint, client: http.DefaultClient, now: time.Now}, nil
+}
+
+// Put uploads the file at once, the parts which have been appended to it before are deleted.
+// The parts are listed before the object is uploaded, so the parts appended meanwhile are kept.
+func (s *S3Storage) Put(name string, r io.Reader) error {
+	parts, err := s.parts(name)
+	if err != nil {
+		return err
+	}
+	if err := s.put(name, r); err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if err := s.delete(part.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put uploads an object, it is spooled to a temporary file since S3 requires the length and the hash of the content
+func (s *S3Storage) put(name string, r io.Reader) error {
+	tmp, err := os.CreateTemp("", "act-artifact-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var body io.ReadCloser = http.NoBody
+	if size > 0 {
+		body = io.NopCloser(tmp)
+	}
+	req, err := s.newRequest(http.MethodPut, name, nil, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	res, err := s.do(req, hex.EncodeToString(h.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Append uploads the data as a part of the file, since S3 can not append to objects. The parts are stored
+// as objects `.parts/<name>/<sequence>` and concatenated with the object of the file when it is read,
+// so appending neither reads the file nor loses the data of concurrent appends.
+func (s *S3Storage) Append(name string, r io.Reader) error {
+	seq := atomic.AddUint64(&s.sequence, 1)
+	random := make([]byte, 4)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	return s.put(partPath(name, fmt.Sprintf("%020d-%010d-%s", s.now().UnixNano(), seq, hex.EncodeToString(random))), r)
+}
+
+func partPath(name, part string) string {
+	return partsDir + name + "/" + part
+}
+
+// parts returns the parts appended to the file in order
+func (s *S3Storage) parts(name string) ([]*ObjectInfo, error) {
+	objects, err := s.list(partsDir + name + "/")
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]*ObjectInfo, 0, len(objects))
+	for _, obj := range objects {
+		// the parts of the files in the directory name are not parts of the file
+		if !strings.Contains(strings.TrimPrefix(obj.Name, partsDir+name+"/"), "/") {
+			parts = append(parts, obj)
+		}
+	}
+	return parts, nil
+}
+
+// objects returns the object of the file, if it has been put, and its parts
+func (s *S3Storage) objects(name string) ([]*ObjectInfo, error) {
+	var ret []*ObjectInfo
+	obj, err := s.stat(name)
+	if err == nil {
+		ret = append(ret, obj)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	parts, err := s.parts(name)
+	if err != nil {
+		return nil, err
+	}
+	ret = append(ret, parts...)
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return ret, nil
+}
+
+func (s *S3Storage) Stat(name string) (*ObjectInfo, error) {
+	objects, err := s.objects(name)
+	if err != nil {
+		return nil, err
+	}
+	info := &ObjectInfo{Name: name}
+	for _, obj := range objects {
+		info.Size += obj.Size
+		if obj.ModTime.After(info.ModTime) {
+			info.ModTime = obj.ModTime
+		}
+	}
+	return info, nil
+}
+
+func (s *S3Storage) stat(name string) (*ObjectInfo, error) {
+	req, err := s.newRequest(http.MethodHead, name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	modTime, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+	return &ObjectInfo{Name: name, Size: res.ContentLength, ModTime: modTime}, nil
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *S3Storage) List(prefix string) ([]*ObjectInfo, error) {
+	objects, err := s.list(prefix)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]*ObjectInfo{}
+	for _, obj := range objects {
+		if !strings.HasPrefix(obj.Name, partsDir) {
+			files[obj.Name] = obj
+		}
+	}
+	// the parts are added to the files they have been appended to
+	parts, err := s.list(partsDir + prefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		name := path.Dir(strings.TrimPrefix(part.Name, partsDir))
+		file, ok := files[name]
+		if !ok {
+			file = &ObjectInfo{Name: name}
+			files[name] = file
+		}
+		file.Size += part.Size
+		if part.ModTime.After(file.ModTime) {
+			file.ModTime = part.ModTime
+		}
+	}
+
+	ret := make([]*ObjectInfo, 0, len(files))
+	for _, file := range files {
+		ret = append(ret, file)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, nil
+}
+
+// list returns the objects whose names start with prefix
+func (s *S3Storage) list(prefix string) ([]*ObjectInfo, error) {
+	var ret []*ObjectInfo
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", s.config.Prefix+prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.newRequest(http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := s.do(req, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		result := &listBucketResult{}
+		err = xml.NewDecoder(res.Body).Decode(result)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid response of listing '%s': %w", prefix, err)
+		}
+
+		for _, obj := range result.Contents {
+			ret = append(ret, &ObjectInfo{
+				Name:    strings.TrimPrefix(obj.Key, s.config.Prefix),
+				Size:    obj.Size,
+				ModTime: obj.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return ret, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *S3Storage) Open(name string, offset, length int64) (io.ReadCloser, error) {
+	objects, err := s.objects(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 1 {
+		return s.open(objects[0].Name, offset, length)
+	}
+
+	// read the ranges of the objects which overlap with the range of the file
+	r := &objectsReader{}
+	for _, obj := range objects {
+		if offset >= obj.Size {
+			offset -= obj.Size
+			continue
+		}
+		if length == 0 {
+			break
+		}
+		n := obj.Size - offset
+		if length > 0 && length < n {
+			n = length
+			length = 0
+		} else if length > 0 {
+			length -= n
+		}
+		key, objOffset := obj.Name, offset
+		r.open = append(r.open, func() (io.ReadCloser, error) {
+			return s.open(key, objOffset, n)
+		})
+		offset = 0
+	}
+	return r, nil
+}
+
+// objectsReader reads the objects one after another, an object is only requested once it is read
+type objectsReader struct {
+	open    []func() (io.ReadCloser, error)
+	current io.ReadCloser
+}
+
+func (r *objectsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.open) == 0 {
+				return 0, io.EOF
+			}
+			current, err := r.open[0]()
+			if err != nil {
+				return 0, err
+			}
+			r.current, r.open = current, r.open[1:]
+		}
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			err = r.current.Close()
+			r.current = nil
+			if n > 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *objectsReader) Close() error {
+	r.open = nil
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+func (s *S3Storage) open(name string, offset, length int64) (io.ReadCloser, error) {
+	req, err := s.newRequest(http.MethodGet, name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if length >= 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	res, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *S3Storage) Delete(name string) error {
+	names := []string{name}
+	// the objects in the directory and the parts of the file and of the files in the directory
+	for _, prefix := range []string{dirPrefix(name), partsDir + dirPrefix(name)} {
+		objects, err := s.list(prefix)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			names = append(names, obj.Name)
+		}
+	}
+	for _, name := range names {
+		if err := s.delete(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3Storage) delete(name string) error {
+	req, err := s.newRequest(http.MethodDelete, name, nil, nil)
+	if err != nil {
+		return err
+	}
+	res, err := s.do(req, emptyPayloadHash)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (s *S3Storage) newRequest(method, name string, query url.Values, body io.ReadCloser) (*http.Request, error) {
+	u := *s.endpoint
+	key := ""
+	if name != "" {
+		key = s.config.Prefix + name
+	}
+	if s.config.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.config.Bucket + "/" + key
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Body = body
+	}
+	return req, nil
+}
+
+// do signs and sends the request, an error is returned if the response is not successful
+func (s *S3Storage) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash)
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	err = fmt.Errorf("%s %s: %s %s", req.Method, req.URL.Path, res.Status, bytes.TrimSpace(body))
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %v", fs.ErrNotExist, err)
+	}
+	return nil, err
+}
+
+// sign adds the headers of AWS Signature Version 4 to the request
+func (s *S3Storage) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") || name == "range" || name == "content-type" || name == "content-md5" {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{date, s.config.Region, "s3", "aws4_request"}, "/")
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hashed[:])}, "\n")
+
+	key := []byte("AWS4" + s.config.SecretAccessKey)
+	for _, v := range []string{date, s.config.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, v)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapePath escapes the path like AWS does, every byte except the unreserved characters and slashes
+func escapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c == '/' || isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery encodes the query sorted by the names like AWS does
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			parts = append(parts, escapeQuery(name)+"="+escapeQuery(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func escapeQuery(s string) string {
+	return strings.ReplaceAll(escapePath(s), "/", "%2F")
+}
+
+func isUnreserved(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '_' || c == '.' || c == '~'
+}
@@ -2,6 +2,7 @@ package artifacts
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -22,6 +23,12 @@ type FileContainerResourceURL struct {
 	FileContainerResourceURL string `json:"fileContainerResourceUrl"`
 }
 
+type CreateArtifactParameters struct {
+	Type          string `json:"Type"`
+	Name          string `json:"Name"`
+	RetentionDays int    `json:"RetentionDays"`
+}
+
 type NamedFileContainerResourceURL struct {
 	Name                     string `json:"name"`
 	FileContainerResourceURL string `json:"fileContainerResourceUrl"`
@@ -86,16 +93,27 @@ func (fwfs readWriteFSImpl) OpenAppendable(name string) (WritableFile, error) {
 	return file, nil
 }
 
+func (fwfs readWriteFSImpl) RemoveAll(name string) error {
+	return os.RemoveAll(name)
+}
+
 var gzipExtension = ".gz__"
 
 func safeResolve(baseDir string, relPath string) string {
 	return filepath.Join(baseDir, filepath.Clean(filepath.Join(string(os.PathSeparator), relPath)))
 }
 
-func uploads(router *httprouter.Router, baseDir string, fsys WriteFS) {
+func uploads(router *httprouter.Router, baseDir string, fsys WriteFS, artifacts *store) {
 	router.POST("/_apis/pipelines/workflows/:runId/artifacts", func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
 		runID := params.ByName("runId")
 
+		var body CreateArtifactParameters
+		if req.Body != nil && json.NewDecoder(req.Body).Decode(&body) == nil && body.Name != "" {
+			if err := artifacts.writeMetadata(artifacts.newMetadata(runID, "", body.Name, body.RetentionDays)); err != nil {
+				panic(err)
+			}
+		}
+
 		json, err := json.Marshal(FileContainerResourceURL{
 			FileContainerResourceURL: fmt.Sprintf("http://%s/upload/%s", req.Host, runID),
 		})
@@ -161,6 +179,12 @@ func uploads(router *httprouter.Router, baseDir string, fsys WriteFS) {
 	})
 
 	router.PATCH("/_apis/pipelines/workflows/:runId/artifacts", func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		if name := req.URL.Query().Get("artifactName"); name != "" {
+			if _, err := artifacts.updateMetadata(params.ByName("runId"), name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				panic(err)
+			}
+		}
+
 		json, err := json.Marshal(ResponseMessage{
 			Message: "success",
 		})
@@ -275,7 +299,49 @@ func downloads(router *httprouter.Router, baseDir string, fsys fs.FS) {
 	})
 }
 
-func Serve(ctx context.Context, artifactPath string, addr string, port string) context.CancelFunc {
+// ServeOption configures the artifact server
+type ServeOption func(c *serveConfig)
+
+type serveConfig struct {
+	retentionDays int
+	sweepInterval time.Duration
+	storage       Storage
+	adminToken    string
+}
+
+// WithStorage stores the artifacts in the storage instead of the artifact path on the local disk,
+// the runners sharing the storage share the artifacts
+func WithStorage(storage Storage) ServeOption {
+	return func(c *serveConfig) {
+		c.storage = storage
+	}
+}
+
+// WithRetentionDays sets the retention of the artifacts which have been uploaded without `retention-days`,
+// 0 keeps them forever
+func WithRetentionDays(days int) ServeOption {
+	return func(c *serveConfig) {
+		c.retentionDays = days
+	}
+}
+
+// WithAdminToken serves the endpoints which list and delete the artifacts of all the runs,
+// the requests have to be authorized with the token.
+// The endpoints are not served without a token, since the jobs can reach the server.
+func WithAdminToken(token string) ServeOption {
+	return func(c *serveConfig) {
+		c.adminToken = token
+	}
+}
+
+// WithSweepInterval sets how often the expired artifacts are deleted
+func WithSweepInterval(interval time.Duration) ServeOption {
+	return func(c *serveConfig) {
+		c.sweepInterval = interval
+	}
+}
+
+func Serve(ctx context.Context, artifactPath string, addr string, port string, options ...ServeOption) context.CancelFunc {
 	serverContext, cancel := context.WithCancel(ctx)
 	logger := common.Logger(serverContext)
 
@@ -283,13 +349,33 @@ func Serve(ctx context.Context, artifactPath string, addr string, port string) c
 		return cancel
 	}
 
+	config := &serveConfig{sweepInterval: time.Hour}
+	for _, o := range options {
+		o(config)
+	}
+
 	router := httprouter.New()
 
 	logger.Debugf("Artifacts base path '%s'", artifactPath)
-	fsys := readWriteFSImpl{}
-	uploads(router, artifactPath, fsys)
+	var fsys ReadWriteFS = readWriteFSImpl{}
+	if config.storage != nil {
+		fsys = newStorageFS(artifactPath, config.storage)
+	}
+	artifacts := newStore(artifactPath, fsys, config.retentionDays)
+	uploads(router, artifactPath, fsys, artifacts)
 	downloads(router, artifactPath, fsys)
 
+	// the signed urls of the artifact API v4 are only valid for this server
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		logger.Fatal(err)
+	}
+	v4 := artifactsV4(router, artifacts, key)
+	publicAPI(router, v4)
+	if config.adminToken != "" {
+		admin(router, v4, config.adminToken)
+	}
+
 	server := &http.Server{
 		Addr:              fmt.Sprintf("%s:%s", addr, port),
 		ReadHeaderTimeout: 2 * time.Second,
@@ -304,6 +390,26 @@ func Serve(ctx context.Context, artifactPath string, addr string, port string) c
 		}
 	}()
 
+	// delete the artifacts whose retention has passed
+	go func() {
+		ticker := time.NewTicker(config.sweepInterval)
+		defer ticker.Stop()
+		for {
+			expired, err := artifacts.sweep()
+			if err != nil {
+				logger.Errorf("Failed to delete expired artifacts: %v", err)
+			}
+			for _, meta := range expired {
+				logger.Infof("Deleted expired artifact '%s' of run '%s'", meta.Name, meta.RunID)
+			}
+			select {
+			case <-serverContext.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
 	// wait for cancel to gracefully shutdown server
 	go func() {
 		<-serverContext.Done()
@@ -66,7 +66,7 @@ func TestNewArtifactUploadPrepare(t *testing.T) {
 	var memfs = fstest.MapFS(map[string]*fstest.MapFile{})
 
 	router := httprouter.New()
-	uploads(router, "artifact/server/path", writeMapFS{memfs})
+	uploads(router, "artifact/server/path", writeMapFS{memfs}, newStore("artifact/server/path", writeMapFS{memfs}, 0))
 
 	req, _ := http.NewRequest("POST", "http://localhost/_apis/pipelines/workflows/1/artifacts", nil)
 	rr := httptest.NewRecorder()
@@ -92,7 +92,7 @@ func TestArtifactUploadBlob(t *testing.T) {
 	var memfs = fstest.MapFS(map[string]*fstest.MapFile{})
 
 	router := httprouter.New()
-	uploads(router, "artifact/server/path", writeMapFS{memfs})
+	uploads(router, "artifact/server/path", writeMapFS{memfs}, newStore("artifact/server/path", writeMapFS{memfs}, 0))
 
 	req, _ := http.NewRequest("PUT", "http://localhost/upload/1?itemPath=some/file", strings.NewReader("content"))
 	rr := httptest.NewRecorder()
@@ -119,7 +119,7 @@ func TestFinalizeArtifactUpload(t *testing.T) {
 	var memfs = fstest.MapFS(map[string]*fstest.MapFile{})
 
 	router := httprouter.New()
-	uploads(router, "artifact/server/path", writeMapFS{memfs})
+	uploads(router, "artifact/server/path", writeMapFS{memfs}, newStore("artifact/server/path", writeMapFS{memfs}, 0))
 
 	req, _ := http.NewRequest("PATCH", "http://localhost/_apis/pipelines/workflows/1/artifacts", nil)
 	rr := httptest.NewRecorder()
@@ -373,7 +373,7 @@ func TestArtifactUploadBlobUnsafePath(t *testing.T) {
 	var memfs = fstest.MapFS(map[string]*fstest.MapFile{})
 
 	router := httprouter.New()
-	uploads(router, "artifact/server/path", writeMapFS{memfs})
+	uploads(router, "artifact/server/path", writeMapFS{memfs}, newStore("artifact/server/path", writeMapFS{memfs}, 0))
 
 	req, _ := http.NewRequest("PUT", "http://localhost/upload/1?itemPath=../../some/file", strings.NewReader("content"))
 	rr := httptest.NewRecorder()
@@ -0,0 +1,326 @@
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Storage stores the files of the artifacts, the names are slash separated paths relative to its root.
+// It returns an error wrapping fs.ErrNotExist if a file does not exist.
+type Storage interface {
+	// Put writes a file, the file is replaced if it exists
+	Put(name string, r io.Reader) error
+	// Append appends to a file, the file is created if it does not exist
+	Append(name string, r io.Reader) error
+	Stat(name string) (*ObjectInfo, error)
+	// List returns the files whose names start with prefix
+	List(prefix string) ([]*ObjectInfo, error)
+	// Open reads length bytes of a file from offset, or the rest of the file if length is negative
+	Open(name string, offset, length int64) (io.ReadCloser, error)
+	// Delete deletes a file, or all the files in it if it is a directory
+	Delete(name string) error
+}
+
+// ObjectInfo describes a file of Storage
+type ObjectInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// NewStorage returns the storage of a url, which is either a path of the local disk, `file:///path`,
+// or `s3://bucket/prefix` whose credentials are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+// The query parameters `endpoint`, `region` and `path-style` configure an S3 compatible object storage.
+func NewStorage(rawURL string) (Storage, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact storage '%s': %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case "", "file":
+		return NewLocalStorage(filepath.FromSlash(u.Host + u.Path)), nil
+	case "s3":
+		query := u.Query()
+		return NewS3Storage(&S3Config{
+			Endpoint:        query.Get("endpoint"),
+			Region:          query.Get("region"),
+			Bucket:          u.Host,
+			Prefix:          strings.TrimPrefix(u.Path, "/"),
+			PathStyle:       query.Get("path-style") == "true",
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		})
+	}
+	return nil, fmt.Errorf("unsupported artifact storage '%s'", rawURL)
+}
+
+// storageFS adapts a Storage to the file system of the artifact server, the paths in root are
+// the files of the storage and the directories are made up from the names of the files
+type storageFS struct {
+	root    string
+	storage Storage
+}
+
+func newStorageFS(root string, storage Storage) storageFS {
+	return storageFS{root: root, storage: storage}
+}
+
+func (fsys storageFS) key(op, name string) (string, error) {
+	rel, err := filepath.Rel(fsys.root, name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (fsys storageFS) Open(name string) (fs.File, error) {
+	key, err := fsys.key("open", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fsys.stat(key)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &storageFile{storage: fsys.storage, info: info}, nil
+}
+
+func (fsys storageFS) Stat(name string) (fs.FileInfo, error) {
+	key, err := fsys.key("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fsys.stat(key)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+func (fsys storageFS) stat(key string) (*objectFileInfo, error) {
+	if key != "" {
+		obj, err := fsys.storage.Stat(key)
+		if err == nil {
+			return &objectFileInfo{ObjectInfo: *obj}, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	objects, err := fsys.storage.List(dirPrefix(key))
+	if err != nil {
+		return nil, err
+	}
+	if key != "" && len(objects) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	return &objectFileInfo{ObjectInfo: ObjectInfo{Name: key}, dir: true}, nil
+}
+
+func (fsys storageFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	key, err := fsys.key("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	prefix := dirPrefix(key)
+	objects, err := fsys.storage.List(prefix)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	if key != "" && len(objects) == 0 {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	children := map[string]*objectFileInfo{}
+	for _, obj := range objects {
+		rel := strings.TrimPrefix(obj.Name, prefix)
+		if i := strings.Index(rel, "/"); i >= 0 {
+			child := rel[:i]
+			info, ok := children[child]
+			if !ok {
+				info = &objectFileInfo{ObjectInfo: ObjectInfo{Name: prefix + child}, dir: true}
+				children[child] = info
+			}
+			if obj.ModTime.After(info.ObjectInfo.ModTime) {
+				info.ObjectInfo.ModTime = obj.ModTime
+			}
+			continue
+		}
+		children[rel] = &objectFileInfo{ObjectInfo: *obj}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, info := range children {
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+func (fsys storageFS) OpenWritable(name string) (WritableFile, error) {
+	key, err := fsys.key("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return newStorageWriter(func(r io.Reader) error {
+		return fsys.storage.Put(key, r)
+	}), nil
+}
+
+func (fsys storageFS) OpenAppendable(name string) (WritableFile, error) {
+	key, err := fsys.key("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return newStorageWriter(func(r io.Reader) error {
+		return fsys.storage.Append(key, r)
+	}), nil
+}
+
+func (fsys storageFS) RemoveAll(name string) error {
+	key, err := fsys.key("remove", name)
+	if err != nil {
+		return err
+	}
+	return fsys.storage.Delete(key)
+}
+
+func dirPrefix(key string) string {
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
+
+type objectFileInfo struct {
+	ObjectInfo
+	dir bool
+}
+
+func (info *objectFileInfo) Name() string {
+	return path.Base(info.ObjectInfo.Name)
+}
+
+func (info *objectFileInfo) Size() int64 {
+	return info.ObjectInfo.Size
+}
+
+func (info *objectFileInfo) Mode() fs.FileMode {
+	if info.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+func (info *objectFileInfo) ModTime() time.Time {
+	return info.ObjectInfo.ModTime
+}
+
+func (info *objectFileInfo) IsDir() bool {
+	return info.dir
+}
+
+func (info *objectFileInfo) Sys() interface{} {
+	return nil
+}
+
+// storageFile reads a file of Storage from the offset it has been seeked to
+type storageFile struct {
+	storage Storage
+	info    *objectFileInfo
+	offset  int64
+	reader  io.ReadCloser
+}
+
+func (f *storageFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *storageFile) Read(p []byte) (int, error) {
+	if f.info.dir {
+		return 0, &fs.PathError{Op: "read", Path: f.info.ObjectInfo.Name, Err: errors.New("is a directory")}
+	}
+	if f.offset >= f.info.Size() {
+		return 0, io.EOF
+	}
+	if f.reader == nil {
+		reader, err := f.storage.Open(f.info.ObjectInfo.Name, f.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		f.reader = reader
+	}
+	n, err := f.reader.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *storageFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.Size()
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if offset != f.offset {
+		if err := f.Close(); err != nil {
+			return 0, err
+		}
+		f.offset = offset
+	}
+	return offset, nil
+}
+
+func (f *storageFile) Close() error {
+	if f.reader == nil {
+		return nil
+	}
+	err := f.reader.Close()
+	f.reader = nil
+	return err
+}
+
+// storageWriter streams the written data to Storage, the data is stored when it is closed
+type storageWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func newStorageWriter(store func(r io.Reader) error) *storageWriter {
+	pr, pw := io.Pipe()
+	w := &storageWriter{pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := store(pr)
+		_ = pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w
+}
+
+func (w *storageWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *storageWriter) Close() error {
+	if err := w.pw.Close(); err != nil {
+		return err
+	}
+	return <-w.done
+}
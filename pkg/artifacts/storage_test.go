@@ -0,0 +1,117 @@
+package artifacts
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeStorageFile(t *testing.T, fsys storageFS, name, content string) {
+	file, err := fsys.OpenWritable(filepath.Join(fsys.root, name))
+	require.NoError(t, err)
+	_, err = io.WriteString(file, content)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
+
+func TestStorageFS(t *testing.T) {
+	for name, storage := range map[string]Storage{
+		"local": NewLocalStorage(t.TempDir()),
+		"s3": func() Storage {
+			_, storage := newTestS3Storage(t, "")
+			return storage
+		}(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			root := "/artifacts"
+			fsys := newStorageFS(root, storage)
+			writeStorageFile(t, fsys, "1/dist/file.txt", "hello")
+			writeStorageFile(t, fsys, "1/dist/sub/file.txt", "world")
+			writeStorageFile(t, fsys, "1/logs/log.txt", "log")
+
+			appendable, err := fsys.OpenAppendable(filepath.Join(root, "1/dist/file.txt"))
+			require.NoError(t, err)
+			_, err = io.WriteString(appendable, " world")
+			require.NoError(t, err)
+			require.NoError(t, appendable.Close())
+
+			var walked []string
+			require.NoError(t, fs.WalkDir(fsys, root, func(path string, entry fs.DirEntry, err error) error {
+				require.NoError(t, err)
+				rel, _ := filepath.Rel(root, path)
+				if entry.IsDir() {
+					rel += "/"
+				}
+				walked = append(walked, filepath.ToSlash(rel))
+				return nil
+			}))
+			assert.Equal([]string{"./", "1/", "1/dist/", "1/dist/file.txt", "1/dist/sub/", "1/dist/sub/file.txt", "1/logs/", "1/logs/log.txt"}, walked)
+
+			info, err := fs.Stat(fsys, filepath.Join(root, "1/dist"))
+			require.NoError(t, err)
+			assert.True(info.IsDir())
+			info, err = fs.Stat(fsys, filepath.Join(root, "1/dist/file.txt"))
+			require.NoError(t, err)
+			assert.False(info.IsDir())
+			assert.Equal(int64(11), info.Size())
+			_, err = fs.Stat(fsys, filepath.Join(root, "1/missing"))
+			assert.ErrorIs(err, fs.ErrNotExist)
+			_, err = fs.Stat(fsys, "/elsewhere/file.txt")
+			assert.ErrorIs(err, fs.ErrInvalid)
+
+			file, err := fsys.Open(filepath.Join(root, "1/dist/file.txt"))
+			require.NoError(t, err)
+			_, err = file.(io.Seeker).Seek(6, io.SeekStart)
+			require.NoError(t, err)
+			data, err := io.ReadAll(file)
+			require.NoError(t, err)
+			assert.Equal("world", string(data))
+			require.NoError(t, file.Close())
+
+			require.NoError(t, fsys.RemoveAll(filepath.Join(root, "1/dist")))
+			entries, err := fs.ReadDir(fsys, filepath.Join(root, "1"))
+			require.NoError(t, err)
+			if assert.Len(entries, 1) {
+				assert.Equal("logs", entries[0].Name())
+			}
+		})
+	}
+}
+
+func TestArtifactsV4S3Storage(t *testing.T) {
+	assert := assert.New(t)
+	fake, storage := newTestS3Storage(t, "act")
+
+	router := httprouter.New()
+	artifactsV4(router, newStore("/artifacts", newStorageFS("/artifacts", storage), 0), []byte("key"))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	artifact := map[string]interface{}{"workflowRunBackendId": "1", "workflowJobRunBackendId": "build", "name": "dist"}
+	created := &CreateArtifactResponse{}
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "CreateArtifact", artifact, created))
+	assert.Equal(http.StatusCreated, putBlob(t, created.SignedUploadURL, "comp=block&blockid=YQ%3D%3D", "shared "))
+	assert.Equal(http.StatusCreated, putBlob(t, created.SignedUploadURL, "comp=block&blockid=Yg%3D%3D", "artifact"))
+	assert.Equal(http.StatusCreated, putBlob(t, created.SignedUploadURL, "comp=blocklist", `<BlockList><Latest>YQ==</Latest><Latest>Yg==</Latest></BlockList>`))
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "FinalizeArtifact", artifact, &FinalizeArtifactResponse{}))
+
+	assert.Equal("shared artifact", string(fake.objects["act/1/dist/artifact.zip"]))
+	assert.Contains(fake.objects, "act/.metadata/1/dist.json")
+
+	signed := &GetSignedArtifactURLResponse{}
+	assert.Equal(http.StatusOK, callArtifactService(t, server, "GetSignedArtifactURL", artifact, signed))
+	res, err := http.Get(signed.SignedURL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal("shared artifact", string(body))
+}
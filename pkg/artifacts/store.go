@@ -0,0 +1,348 @@
+package artifacts
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const artifactMetadataDir = ".metadata"
+
+// ArtifactMetadata is stored next to the files of an artifact
+type ArtifactMetadata struct {
+	ID            int64      `json:"id,omitempty"` // the unique id of the artifact, which is assigned when it is created
+	RunID         string     `json:"runId"`
+	JobID         string     `json:"jobId,omitempty"`
+	Name          string     `json:"name"`
+	Size          int64      `json:"size"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	RetentionDays int        `json:"retentionDays,omitempty"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+	Pending       bool       `json:"pending,omitempty"` // the artifact has been created but not finalized yet
+}
+
+// id returns the unique id of the artifact, the artifacts which have been stored without one
+// are identified by the hash of their run and name
+func (m *ArtifactMetadata) id() jsonInt64 {
+	if m.ID != 0 {
+		return jsonInt64(m.ID)
+	}
+	return artifactID(m.RunID, m.Name)
+}
+
+// newArtifactID returns a random id which is a safe integer of JavaScript, since the clients parse it as a number.
+// The ids are larger than the hashes of artifactID, so they never collide with the ids of the artifacts stored without one.
+func newArtifactID() int64 {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return int64(binary.BigEndian.Uint64(b[:])&(1<<52-1) | 1<<52)
+}
+
+// Expired returns true if the retention of the artifact has passed
+func (m *ArtifactMetadata) Expired(now time.Time) bool {
+	return m.ExpiresAt != nil && !now.Before(*m.ExpiresAt)
+}
+
+// store keeps the files of the artifacts in `<baseDir>/<runId>/<name>/` and their metadata in
+// `<baseDir>/.metadata/<runId>/<name>.json`
+type store struct {
+	baseDir string
+	fsys    ReadWriteFS
+	// retentionDays is the retention of the artifacts which have not been uploaded with one, 0 keeps them forever
+	retentionDays int
+	now           func() time.Time
+}
+
+func newStore(baseDir string, fsys ReadWriteFS, retentionDays int) *store {
+	return &store{baseDir: baseDir, fsys: fsys, retentionDays: retentionDays, now: time.Now}
+}
+
+func (s *store) artifactPath(runID, name string, elem ...string) string {
+	return safeResolve(safeResolve(safeResolve(s.baseDir, runID), name), filepath.Join(elem...))
+}
+
+func (s *store) metadataPath(runID, name string) string {
+	return safeResolve(safeResolve(safeResolve(s.baseDir, artifactMetadataDir), runID), name+".json")
+}
+
+// newMetadata returns the metadata of a new artifact, the retention is applied from the creation
+func (s *store) newMetadata(runID, jobID, name string, retentionDays int) *ArtifactMetadata {
+	if retentionDays <= 0 {
+		retentionDays = s.retentionDays
+	}
+	meta := &ArtifactMetadata{
+		ID:        newArtifactID(),
+		RunID:     runID,
+		JobID:     jobID,
+		Name:      name,
+		CreatedAt: s.now().UTC(),
+	}
+	if retentionDays > 0 {
+		expiresAt := meta.CreatedAt.AddDate(0, 0, retentionDays)
+		meta.RetentionDays = retentionDays
+		meta.ExpiresAt = &expiresAt
+	}
+	return meta
+}
+
+func (s *store) writeMetadata(meta *ArtifactMetadata) error {
+	file, err := s.fsys.OpenWritable(s.metadataPath(meta.RunID, meta.Name))
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(meta); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readMetadata returns the metadata of an artifact, the metadata of the artifacts which have been stored
+// without it is made up from their files
+func (s *store) readMetadata(runID, name string) (*ArtifactMetadata, error) {
+	info, err := fs.Stat(s.fsys, s.artifactPath(runID, name))
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("artifact '%s' of run '%s': %w", name, runID, fs.ErrNotExist)
+	}
+
+	meta := &ArtifactMetadata{}
+	file, err := s.fsys.Open(s.metadataPath(runID, name))
+	if err == nil {
+		defer file.Close()
+		if err := json.NewDecoder(file).Decode(meta); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid metadata of artifact '%s' of run '%s': %w", name, runID, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	meta.RunID = runID
+	meta.Name = name
+
+	size, modTime, err := s.artifactInfo(runID, name)
+	if err != nil {
+		return nil, err
+	}
+	meta.Size = size
+	if meta.CreatedAt.IsZero() {
+		meta.CreatedAt = modTime.UTC()
+	}
+	return meta, nil
+}
+
+// updateMetadata updates the size of an artifact after its files have been uploaded, it is finalized then
+func (s *store) updateMetadata(runID, name string) (*ArtifactMetadata, error) {
+	meta, err := s.readMetadata(runID, name)
+	if err != nil {
+		return nil, err
+	}
+	meta.Pending = false
+	return meta, s.writeMetadata(meta)
+}
+
+// artifactInfo returns the size and the modification time of the files of an artifact
+func (s *store) artifactInfo(runID, name string) (int64, time.Time, error) {
+	var size int64
+	var modTime time.Time
+	err := fs.WalkDir(s.fsys, s.artifactPath(runID, name), func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			size += info.Size()
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+		return nil
+	})
+	return size, modTime, err
+}
+
+// list returns the artifacts which have not expired, the artifacts of all the runs are returned if runID is empty
+// and the artifacts of all the names if name is empty
+func (s *store) list(runID, name string) ([]*ArtifactMetadata, error) {
+	return s.find(runID, name, func(meta *ArtifactMetadata) bool {
+		return !meta.Expired(s.now())
+	})
+}
+
+func (s *store) find(runID, name string, filter func(*ArtifactMetadata) bool) ([]*ArtifactMetadata, error) {
+	runIDs := []string{runID}
+	if runID == "" {
+		var err error
+		if runIDs, err = s.dirs(s.baseDir); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := []*ArtifactMetadata{}
+	for _, runID := range runIDs {
+		names := []string{name}
+		if name == "" {
+			var err error
+			if names, err = s.dirs(safeResolve(s.baseDir, runID)); err != nil {
+				return nil, err
+			}
+		}
+		for _, name := range names {
+			meta, err := s.readMetadata(runID, name)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			if filter(meta) {
+				ret = append(ret, meta)
+			}
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].RunID != ret[j].RunID {
+			return ret[i].RunID < ret[j].RunID
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, nil
+}
+
+// dirs returns the names of the directories in dir, the internal directories like the metadata are skipped
+func (s *store) dirs(dir string) ([]string, error) {
+	entries, err := fs.ReadDir(s.fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var ret []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			ret = append(ret, entry.Name())
+		}
+	}
+	return ret, nil
+}
+
+// remove deletes the files and the metadata of an artifact
+func (s *store) remove(runID, name string) error {
+	r, ok := s.fsys.(remover)
+	if !ok {
+		return errors.New("artifacts can not be deleted")
+	}
+	if err := r.RemoveAll(s.artifactPath(runID, name)); err != nil {
+		return err
+	}
+	if err := r.RemoveAll(s.metadataPath(runID, name)); err != nil {
+		return err
+	}
+	if entries, err := fs.ReadDir(s.fsys, safeResolve(s.baseDir, runID)); err == nil && len(entries) == 0 {
+		return r.RemoveAll(safeResolve(s.baseDir, runID))
+	}
+	return nil
+}
+
+// sweep deletes the artifacts whose retention has passed
+func (s *store) sweep() ([]*ArtifactMetadata, error) {
+	expired, err := s.find("", "", func(meta *ArtifactMetadata) bool {
+		return meta.Expired(s.now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, meta := range expired {
+		if err := s.remove(meta.RunID, meta.Name); err != nil {
+			return nil, fmt.Errorf("delete artifact '%s' of run '%s': %w", meta.Name, meta.RunID, err)
+		}
+	}
+	return expired, nil
+}
+
+// writeArchive writes the artifact as zip, the artifacts uploaded with the legacy API are zipped on the fly
+func (s *store) writeArchive(w io.Writer, runID, name string) error {
+	if err := s.copyFile(w, s.artifactPath(runID, name, artifactV4File)); !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return s.writeZip(w, s.artifactPath(runID, name))
+}
+
+func (s *store) copyFile(w io.Writer, name string) error {
+	file, err := s.fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// writeZip zips the files of a legacy artifact, the files which have been uploaded with gzip are decompressed
+func (s *store) writeZip(w io.Writer, dir string) error {
+	var files []string
+	err := fs.WalkDir(s.fsys, dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	zw := zip.NewWriter(w)
+	for _, path := range files {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if err := s.writeZipEntry(zw, filepath.ToSlash(rel), path); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (s *store) writeZipEntry(zw *zip.Writer, name, path string) error {
+	file, err := s.fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(name, gzipExtension) {
+		name = strings.TrimSuffix(name, gzipExtension)
+		gr, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	entry, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, r)
+	return err
+}
@@ -0,0 +1,110 @@
+package artifacts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T, retentionDays int, now time.Time) *store {
+	s := newStore(t.TempDir(), readWriteFSImpl{}, retentionDays)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func writeArtifact(t *testing.T, s *store, runID, name string, retentionDays int, content string) {
+	require.NoError(t, os.MkdirAll(s.artifactPath(runID, name), 0o755))
+	require.NoError(t, os.WriteFile(s.artifactPath(runID, name, "file.txt"), []byte(content), 0o644))
+	if retentionDays >= 0 {
+		require.NoError(t, s.writeMetadata(s.newMetadata(runID, "build", name, retentionDays)))
+	}
+}
+
+func TestStoreNewMetadata(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	meta := newTestStore(t, 0, now).newMetadata("1", "build", "dist", 0)
+	assert.Nil(t, meta.ExpiresAt)
+	// the ids are unique and never collide with the hashes of the artifacts stored without an id
+	assert.Greater(t, meta.ID, int64(artifactID("1", "dist")))
+	assert.NotEqual(t, meta.ID, newTestStore(t, 0, now).newMetadata("1", "build", "dist", 0).ID)
+	assert.False(t, meta.Expired(now.AddDate(10, 0, 0)))
+
+	meta = newTestStore(t, 90, now).newMetadata("1", "build", "dist", 0)
+	assert.Equal(t, 90, meta.RetentionDays)
+	assert.Equal(t, now.AddDate(0, 0, 90), *meta.ExpiresAt)
+
+	meta = newTestStore(t, 90, now).newMetadata("1", "build", "dist", 5)
+	assert.Equal(t, 5, meta.RetentionDays)
+	assert.False(t, meta.Expired(now.AddDate(0, 0, 5).Add(-time.Second)))
+	assert.True(t, meta.Expired(now.AddDate(0, 0, 5)))
+}
+
+func TestStoreList(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestStore(t, 0, now)
+	writeArtifact(t, s, "1", "dist", 1, "12345")
+	writeArtifact(t, s, "1", "logs", 0, "1")
+	writeArtifact(t, s, "2", "dist", -1, "123")
+
+	list, err := s.list("", "")
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, "1", list[0].RunID)
+	assert.Equal(t, "dist", list[0].Name)
+	assert.Equal(t, "build", list[0].JobID)
+	assert.Equal(t, int64(5), list[0].Size)
+	assert.Equal(t, now, list[0].CreatedAt)
+	// the metadata of the artifacts stored without it is made up from the files
+	assert.Equal(t, "2", list[2].RunID)
+	assert.Equal(t, int64(3), list[2].Size)
+	assert.Empty(t, list[2].JobID)
+
+	list, err = s.list("", "dist")
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	list, err = s.list("1", "")
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	list, err = s.list("3", "")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	s.now = func() time.Time { return now.AddDate(0, 0, 2) }
+	list, err = s.list("1", "")
+	require.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, "logs", list[0].Name)
+	}
+}
+
+func TestStoreSweep(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestStore(t, 0, now)
+	writeArtifact(t, s, "1", "dist", 1, "dist")
+	writeArtifact(t, s, "2", "dist", 3, "dist")
+	writeArtifact(t, s, "2", "logs", 1, "logs")
+
+	expired, err := s.sweep()
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+
+	s.now = func() time.Time { return now.AddDate(0, 0, 1) }
+	expired, err = s.sweep()
+	require.NoError(t, err)
+	require.Len(t, expired, 2)
+	assert.Equal(t, "1", expired[0].RunID)
+	assert.Equal(t, "logs", expired[1].Name)
+
+	assert.NoDirExists(t, filepath.Join(s.baseDir, "1"))
+	assert.NoFileExists(t, s.metadataPath("2", "logs"))
+	assert.NoDirExists(t, s.artifactPath("2", "logs"))
+	assert.DirExists(t, s.artifactPath("2", "dist"))
+	assert.FileExists(t, s.metadataPath("2", "dist"))
+}
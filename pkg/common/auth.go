@@ -0,0 +1,189 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenKey signs the tokens, it is random unless it is set with SetAuthorizationTokenKey,
+// so only the servers of this process can verify them by default
+var (
+	tokenKeyMu sync.RWMutex
+	tokenKey   = func() []byte {
+		key := make([]byte, 32)
+		_, _ = rand.Read(key)
+		return key
+	}()
+)
+
+// minTokenKeySize is the minimum size of a key set with SetAuthorizationTokenKey
+const minTokenKeySize = 16
+
+// SetAuthorizationTokenKey sets the key which signs and verifies the tokens, the artifact and cache servers
+// of other processes with the same key accept the tokens created by this process.
+// It should be called before any token is created, the tokens signed with the previous key become invalid.
+func SetAuthorizationTokenKey(key []byte) error {
+	if len(key) < minTokenKeySize {
+		return fmt.Errorf("the token key must have at least %d bytes", minTokenKeySize)
+	}
+	tokenKeyMu.Lock()
+	defer tokenKeyMu.Unlock()
+	tokenKey = append([]byte(nil), key...)
+	return nil
+}
+
+// the permissions of the cache scopes in the `ac` claim
+const (
+	cachePermissionRead  = 1
+	cachePermissionWrite = 2
+)
+
+// CacheScope is the repository and the refs whose caches a job can access, the caches are saved to Ref
+// and restored from Ref and then from RestoreRefs in order, like the base ref of a pull request and the default branch
+type CacheScope struct {
+	Repository  string
+	Ref         string
+	RestoreRefs []string
+}
+
+// Refs returns the refs to restore the caches from in order
+func (s *CacheScope) Refs() []string {
+	refs := []string{s.Ref}
+	for _, ref := range s.RestoreRefs {
+		if ref != "" && !contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type cacheAccess struct {
+	Scope      string `json:"Scope"`
+	Permission int    `json:"Permission"`
+}
+
+// AuthorizationClaims are the claims of a token created with CreateAuthorizationToken
+type AuthorizationClaims struct {
+	Scopes     []string
+	CacheScope *CacheScope
+}
+
+// CreateAuthorizationToken creates a JWT for ACTIONS_RUNTIME_TOKEN, the artifact and cache clients read
+// the ids of the run and the job from the scopes in its `scp` claim.
+// The ids must not contain colons or spaces.
+// The cache scope is put in the `ac` claim the way GitHub does, it is omitted if nil.
+func CreateAuthorizationToken(runID, jobID string, cacheScope *CacheScope) (string, error) {
+	if strings.ContainsAny(runID+jobID, ": ") {
+		return "", fmt.Errorf("invalid run id '%s' or job id '%s'", runID, jobID)
+	}
+	now := time.Now()
+	claims := map[string]interface{}{
+		"scp": strings.Join([]string{
+			"Actions.GenericRead:00000000-0000-0000-0000-000000000000",
+			fmt.Sprintf("Actions.UploadArtifacts:%s:%s", runID, jobID),
+			fmt.Sprintf("Actions.Results:%s:%s", runID, jobID),
+		}, " "),
+		"iat": now.Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(24 * time.Hour).Unix(),
+	}
+	if cacheScope != nil {
+		access := []cacheAccess{{Scope: cacheScope.Ref, Permission: cachePermissionRead | cachePermissionWrite}}
+		for _, ref := range cacheScope.Refs()[1:] {
+			access = append(access, cacheAccess{Scope: ref, Permission: cachePermissionRead})
+		}
+		ac, err := json.Marshal(access)
+		if err != nil {
+			return "", err
+		}
+		claims["ac"] = string(ac)
+		claims["repository"] = cacheScope.Repository
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + signToken(unsigned), nil
+}
+
+// ParseAuthorizationToken verifies a token created with CreateAuthorizationToken and returns its claims
+func ParseAuthorizationToken(token string) (*AuthorizationClaims, error) {
+	unsigned, signature, ok := cutLast(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signToken(unsigned))) {
+		return nil, errors.New("invalid token signature")
+	}
+	_, encoded, _ := strings.Cut(unsigned, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token payload: %w", err)
+	}
+
+	claims := struct {
+		Scp        string `json:"scp"`
+		Exp        int64  `json:"exp"`
+		Nbf        int64  `json:"nbf"`
+		AC         string `json:"ac"`
+		Repository string `json:"repository"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("invalid token payload: %w", err)
+	}
+	if now := time.Now().Unix(); now >= claims.Exp || now < claims.Nbf {
+		return nil, errors.New("the token has expired or is not valid yet")
+	}
+
+	ret := &AuthorizationClaims{Scopes: strings.Fields(claims.Scp)}
+	if claims.AC != "" {
+		var access []cacheAccess
+		if err := json.Unmarshal([]byte(claims.AC), &access); err != nil {
+			return nil, fmt.Errorf("invalid claim 'ac': %w", err)
+		}
+		ret.CacheScope = &CacheScope{Repository: claims.Repository}
+		for _, a := range access {
+			if a.Permission&cachePermissionWrite != 0 && ret.CacheScope.Ref == "" {
+				ret.CacheScope.Ref = a.Scope
+			} else if a.Permission&cachePermissionRead != 0 {
+				ret.CacheScope.RestoreRefs = append(ret.CacheScope.RestoreRefs, a.Scope)
+			}
+		}
+	}
+	return ret, nil
+}
+
+func signToken(unsigned string) string {
+	tokenKeyMu.RLock()
+	mac := hmac.New(sha256.New, tokenKey)
+	tokenKeyMu.RUnlock()
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
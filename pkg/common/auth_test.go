@@ -0,0 +1,83 @@
+package common
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAuthorizationToken(t *testing.T) {
+	token, err := CreateAuthorizationToken("1", "build", nil)
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+
+	claims := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Contains(t, strings.Split(claims["scp"].(string), " "), "Actions.Results:1:build")
+	assert.NotContains(t, claims, "ac")
+
+	_, err = CreateAuthorizationToken("1", "build:1", nil)
+	assert.Error(t, err)
+}
+
+func TestParseAuthorizationToken(t *testing.T) {
+	scope := &CacheScope{
+		Repository:  "owner/repo",
+		Ref:         "refs/pull/1/merge",
+		RestoreRefs: []string{"refs/heads/main", "refs/heads/main", ""},
+	}
+	token, err := CreateAuthorizationToken("1", "build", scope)
+	require.NoError(t, err)
+
+	claims, err := ParseAuthorizationToken(token)
+	require.NoError(t, err)
+	assert.Contains(t, claims.Scopes, "Actions.UploadArtifacts:1:build")
+	assert.Equal(t, &CacheScope{
+		Repository:  "owner/repo",
+		Ref:         "refs/pull/1/merge",
+		RestoreRefs: []string{"refs/heads/main"},
+	}, claims.CacheScope)
+	assert.Equal(t, []string{"refs/pull/1/merge", "refs/heads/main"}, claims.CacheScope.Refs())
+
+	token, err = CreateAuthorizationToken("1", "build", nil)
+	require.NoError(t, err)
+	claims, err = ParseAuthorizationToken(token)
+	require.NoError(t, err)
+	assert.Nil(t, claims.CacheScope)
+
+	for _, invalid := range []string{"", "token", token + "x", strings.Replace(token, ".", ".e30", 1)} {
+		_, err := ParseAuthorizationToken(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSetAuthorizationTokenKey(t *testing.T) {
+	tokenKeyMu.RLock()
+	key := tokenKey
+	tokenKeyMu.RUnlock()
+	defer func() {
+		require.NoError(t, SetAuthorizationTokenKey(key))
+	}()
+
+	assert.Error(t, SetAuthorizationTokenKey([]byte("short")))
+
+	token, err := CreateAuthorizationToken("1", "build", nil)
+	require.NoError(t, err)
+	require.NoError(t, SetAuthorizationTokenKey([]byte("0123456789abcdef0123456789abcdef")))
+	// the tokens signed with the previous key are invalid
+	_, err = ParseAuthorizationToken(token)
+	assert.Error(t, err)
+
+	token, err = CreateAuthorizationToken("1", "build", nil)
+	require.NoError(t, err)
+	_, err = ParseAuthorizationToken(token)
+	assert.NoError(t, err)
+}
@@ -8,12 +8,14 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/mattn/go-isatty"
@@ -160,6 +162,79 @@ func FindGitRef(ctx context.Context, file string) (string, error) {
 	return "", fmt.Errorf("failed to identify reference (tag/branch) for the checked-out revision '%s'", ref)
 }
 
+// FindChangedFiles get the files changed between the merge base of baseRef and HEAD
+func FindChangedFiles(ctx context.Context, file, baseRef string) ([]string, error) {
+	logger := common.Logger(ctx)
+
+	repo, err := git.PlainOpenWithOptions(
+		file,
+		&git.PlainOpenOptions{
+			DetectDotGit:          true,
+			EnableDotGitCommonDir: true,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	baseHash, err := repo.ResolveRevision(plumbing.Revision(baseRef))
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve base ref '%s': %w", baseRef, err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	baseCommit, err := repo.CommitObject(*baseHash)
+	if err != nil {
+		return nil, err
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	// compare against the merge base like GitHub does for pull requests
+	mergeBases, err := baseCommit.MergeBase(headCommit)
+	if err != nil {
+		return nil, err
+	}
+	if len(mergeBases) > 0 {
+		baseCommit = mergeBases[0]
+	}
+	logger.Debugf("Comparing '%s' with merge base '%s'", head.Hash(), baseCommit.Hash)
+
+	baseTree, err := baseCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTreeWithOptions(ctx, baseTree, headTree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	files := make([]string, 0, len(changes))
+	for _, change := range changes {
+		// renamed files are reported with both names
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				files = append(files, name)
+			}
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
 // FindGithubRepo get the repo
 func FindGithubRepo(ctx context.Context, file, githubInstance, remoteName string) (string, error) {
 	if remoteName == "" {
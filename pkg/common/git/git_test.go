@@ -177,6 +177,36 @@ func TestGitFindRef(t *testing.T) {
 	}
 }
 
+func TestGitFindChangedFiles(t *testing.T) {
+	basedir := testDir(t)
+	gitConfig()
+
+	require.NoError(t, gitCmd("-C", basedir, "init", "--initial-branch=master"))
+	require.NoError(t, cleanGitHooks(basedir))
+
+	writeAndCommit := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(basedir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(basedir, name), []byte(content), 0o644))
+		require.NoError(t, gitCmd("-C", basedir, "add", name))
+		require.NoError(t, gitCmd("-C", basedir, "commit", "-m", name))
+	}
+
+	writeAndCommit("README.md", "readme")
+	require.NoError(t, gitCmd("-C", basedir, "checkout", "-b", "feature"))
+	writeAndCommit("docs/index.md", "docs")
+	writeAndCommit("src/main.go", "package main")
+	require.NoError(t, gitCmd("-C", basedir, "checkout", "master"))
+	writeAndCommit("CHANGELOG.md", "changes on master")
+	require.NoError(t, gitCmd("-C", basedir, "checkout", "feature"))
+
+	files, err := FindChangedFiles(context.Background(), basedir, "master")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs/index.md", "src/main.go"}, files)
+
+	_, err = FindChangedFiles(context.Background(), basedir, "does-not-exist")
+	assert.Error(t, err)
+}
+
 func TestGitCloneExecutor(t *testing.T) {
 	for name, tt := range map[string]struct {
 		Err      error
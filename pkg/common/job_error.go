@@ -28,3 +28,12 @@ func WithJobErrorContainer(ctx context.Context) context.Context {
 	container := map[string]error{}
 	return context.WithValue(ctx, jobErrorContextKeyVal, container)
 }
+
+// WithJobErrorContainerOf adds the error container of parent to the context,
+// so that a detached context still shares the job error with parent
+func WithJobErrorContainerOf(ctx context.Context, parent context.Context) context.Context {
+	if val := parent.Value(jobErrorContextKeyVal); val != nil {
+		return context.WithValue(ctx, jobErrorContextKeyVal, val)
+	}
+	return ctx
+}
@@ -0,0 +1,94 @@
+package jobparser
+
+import (
+	"strings"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+// defaultTypes are the activity types which trigger a workflow if `types` is not specified
+// and they differ from all the activity types of the event
+var defaultTypes = map[string][]string{
+	"pull_request":        {"opened", "synchronize", "reopened"},
+	"pull_request_target": {"opened", "synchronize", "reopened"},
+}
+
+// Match reports whether the event triggers the workflow, the payload is the webhook payload of the event
+// and the changed files are matched against the path filters, which are not applied if they are nil.
+// The branches, tags and paths filters are applied with model.MatchEventFilters like the runner applies them
+// when planning, an invalid filter never matches.
+func (evt *Event) Match(eventName string, payload map[string]interface{}, changedFiles []string) bool {
+	if evt.Name != eventName {
+		return false
+	}
+	if evt.IsSchedule() {
+		return true
+	}
+
+	if !evt.matchTypes(stringOf(payload, "action")) {
+		return false
+	}
+
+	switch eventName {
+	case "push":
+		match, err := model.MatchEventFilters(eventName, evt.acts, &model.EventFilter{
+			Ref:          stringOf(payload, "ref"),
+			ChangedFiles: changedFiles,
+		})
+		return err == nil && match
+	case "pull_request", "pull_request_target":
+		ref := stringOf(payload, "pull_request", "base", "ref")
+		if !strings.HasPrefix(ref, "refs/") {
+			ref = "refs/heads/" + ref
+		}
+		match, err := model.MatchEventFilters(eventName, evt.acts, &model.EventFilter{
+			Ref:          ref,
+			ChangedFiles: changedFiles,
+		})
+		return err == nil && match
+	case "workflow_run":
+		if workflows := evt.acts["workflows"]; len(workflows) > 0 {
+			if match, err := model.MatchPatterns(workflows, nil, stringOf(payload, "workflow_run", "name")); err != nil || !match {
+				return false
+			}
+		}
+		branches, hasBranches := evt.acts["branches"]
+		branchesIgnore, hasBranchesIgnore := evt.acts["branches-ignore"]
+		if hasBranches && hasBranchesIgnore {
+			return false
+		}
+		match, err := model.MatchPatterns(branches, branchesIgnore, stringOf(payload, "workflow_run", "head_branch"))
+		return err == nil && match
+	}
+	return true
+}
+
+func (evt *Event) matchTypes(action string) bool {
+	types, ok := evt.acts["types"]
+	if !ok {
+		types = defaultTypes[evt.Name]
+	}
+	if len(types) == 0 || action == "" {
+		return true
+	}
+	for _, t := range types {
+		if t == action {
+			return true
+		}
+	}
+	return false
+}
+
+// stringOf returns the string at the path of the payload, or an empty string if there is none
+func stringOf(payload map[string]interface{}, path ...string) string {
+	var value interface{} = payload
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = m[key]
+	}
+	s, _ := value.(string)
+	return s
+}
@@ -0,0 +1,79 @@
+package jobparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestEventMatch(t *testing.T) {
+	push := func(ref string) map[string]interface{} {
+		return map[string]interface{}{"ref": ref}
+	}
+	pullRequest := func(action, base string) map[string]interface{} {
+		return map[string]interface{}{
+			"action":       action,
+			"pull_request": map[string]interface{}{"base": map[string]interface{}{"ref": base}},
+		}
+	}
+	workflowRun := func(name, branch string) map[string]interface{} {
+		return map[string]interface{}{
+			"action":       "completed",
+			"workflow_run": map[string]interface{}{"name": name, "head_branch": branch},
+		}
+	}
+
+	kases := []struct {
+		name         string
+		on           string
+		eventName    string
+		payload      map[string]interface{}
+		changedFiles []string
+		match        bool
+	}{
+		{"other event", "on: push", "pull_request", pullRequest("opened", "main"), nil, false},
+		{"no filters", "on: push", "push", push("refs/heads/main"), nil, true},
+		{"branches", "on:\n  push:\n    branches: [main, 'releases/**']", "push", push("refs/heads/releases/v1"), nil, true},
+		{"branches not matched", "on:\n  push:\n    branches: [main]", "push", push("refs/heads/dev"), nil, false},
+		{"branches-ignore", "on:\n  push:\n    branches-ignore: ['dev/*']", "push", push("refs/heads/dev/feature"), nil, false},
+		{"branches and branches-ignore", "on:\n  push:\n    branches: [main]\n    branches-ignore: [dev]", "push", push("refs/heads/main"), nil, false},
+		{"negated branches", "on:\n  push:\n    branches: ['releases/**', '!releases/**-alpha']", "push", push("refs/heads/releases/v1-alpha"), nil, false},
+		{"tags", "on:\n  push:\n    tags: ['v*']", "push", push("refs/tags/v1.0.0"), nil, true},
+		{"tags-ignore", "on:\n  push:\n    tags-ignore: ['v*']", "push", push("refs/tags/v1.0.0"), nil, false},
+		{"branch push with only tags", "on:\n  push:\n    tags: ['v*']", "push", push("refs/heads/main"), nil, false},
+		{"tag push with only branches", "on:\n  push:\n    branches: [main]", "push", push("refs/tags/v1.0.0"), nil, false},
+		{"paths", "on:\n  push:\n    paths: ['src/**']", "push", push("refs/heads/main"), []string{"README.md", "src/main.go"}, true},
+		{"paths not matched", "on:\n  push:\n    paths: ['src/**']", "push", push("refs/heads/main"), []string{"README.md"}, false},
+		{"paths unknown", "on:\n  push:\n    paths: ['src/**']", "push", push("refs/heads/main"), nil, true},
+		{"paths-ignore", "on:\n  push:\n    paths-ignore: ['docs/**']", "push", push("refs/heads/main"), []string{"docs/index.md"}, false},
+		{"paths-ignore partially", "on:\n  push:\n    paths-ignore: ['docs/**']", "push", push("refs/heads/main"), []string{"docs/index.md", "main.go"}, true},
+		{"paths of tag push", "on:\n  push:\n    tags: ['v*']\n    paths: ['src/**']", "push", push("refs/tags/v1"), []string{"README.md"}, true},
+		{"pull_request default types", "on: pull_request", "pull_request", pullRequest("synchronize", "main"), nil, true},
+		{"pull_request not default type", "on: pull_request", "pull_request", pullRequest("labeled", "main"), nil, false},
+		{"pull_request types", "on:\n  pull_request:\n    types: [labeled]", "pull_request", pullRequest("labeled", "main"), nil, true},
+		{"pull_request types not matched", "on:\n  pull_request:\n    types: [labeled]", "pull_request", pullRequest("opened", "main"), nil, false},
+		{"pull_request filters for pull_request_target", "on:\n  pull_request:\n    branches: [main]", "pull_request_target", pullRequest("opened", "main"), nil, false},
+		{"pull_request_target base branch", "on:\n  pull_request_target:\n    branches: [main]", "pull_request_target", pullRequest("opened", "main"), nil, true},
+		{"pull_request base branch not matched", "on:\n  pull_request:\n    branches: [main]", "pull_request", pullRequest("opened", "dev"), nil, false},
+		{"issues types", "on:\n  issues:\n    types: [opened, edited]", "issues", map[string]interface{}{"action": "edited"}, nil, true},
+		{"issues types not matched", "on:\n  issues:\n    types: [opened]", "issues", map[string]interface{}{"action": "closed"}, nil, false},
+		{"workflow_run", "on:\n  workflow_run:\n    workflows: [Build]\n    types: [completed]", "workflow_run", workflowRun("Build", "main"), nil, true},
+		{"workflow_run not matched", "on:\n  workflow_run:\n    workflows: [Build]", "workflow_run", workflowRun("Test", "main"), nil, false},
+		{"workflow_run branches", "on:\n  workflow_run:\n    workflows: [Build]\n    branches: [main]", "workflow_run", workflowRun("Build", "dev"), nil, false},
+		{"schedule", "on:\n  schedule:\n    - cron: '0 0 * * *'", "schedule", nil, nil, true},
+	}
+	for _, kase := range kases {
+		t.Run(kase.name, func(t *testing.T) {
+			origin := struct {
+				On yaml.Node `yaml:"on"`
+			}{}
+			require.NoError(t, yaml.Unmarshal([]byte(kase.on), &origin))
+			events, err := ParseRawOn(&origin.On)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, kase.match, events[0].Match(kase.eventName, kase.payload, kase.changedFiles))
+		})
+	}
+}
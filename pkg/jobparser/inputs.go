@@ -0,0 +1,131 @@
+package jobparser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+// workflowInput is the declaration of an input of `workflow_dispatch` or `workflow_call`
+type workflowInput struct {
+	Required bool
+	Default  string
+	Type     string
+	Options  []string
+}
+
+// getInputs returns the inputs of the workflow, the passed values are coerced to the declared types
+// and the defaults are applied for the missing ones
+func getInputs(workflow *model.Workflow, eventName string, values map[string]interface{}) (map[string]interface{}, error) {
+	declared := getDeclaredInputs(workflow, eventName)
+
+	names := make([]string, 0, len(declared))
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	inputs := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		// undeclared inputs are passed as they are
+		inputs[name] = value
+	}
+	for _, name := range names {
+		input := declared[name]
+		value, ok := values[name]
+		if !ok || value == nil {
+			if input.Default == "" && input.Required {
+				return nil, fmt.Errorf("input '%s' is required", name)
+			}
+			value = input.Default
+		}
+		coerced, err := coerceInput(input, value)
+		if err != nil {
+			return nil, fmt.Errorf("input '%s': %w", name, err)
+		}
+		inputs[name] = coerced
+	}
+	return inputs, nil
+}
+
+// getDeclaredInputs returns the inputs declared for the event, the inputs of `workflow_dispatch`
+// are preferred if the event is unknown
+func getDeclaredInputs(workflow *model.Workflow, eventName string) map[string]workflowInput {
+	declared := map[string]workflowInput{}
+	if eventName == "" || eventName == "workflow_dispatch" {
+		if config := workflow.WorkflowDispatchConfig(); config != nil && len(config.Inputs) > 0 {
+			for name, input := range config.Inputs {
+				declared[name] = workflowInput{
+					Required: input.Required,
+					Default:  input.Default,
+					Type:     input.Type,
+					Options:  input.Options,
+				}
+			}
+			return declared
+		}
+	}
+	if eventName == "" || eventName == "workflow_call" {
+		for name, input := range workflow.WorkflowCallConfig().Inputs {
+			declared[name] = workflowInput{
+				Required: input.Required,
+				Default:  input.Default,
+				Type:     input.Type,
+			}
+		}
+	}
+	return declared
+}
+
+func coerceInput(input workflowInput, value interface{}) (interface{}, error) {
+	switch input.Type {
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if v == "" {
+				return false, nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("'%s' is not a boolean", v)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("'%v' is not a boolean", value)
+	case "number":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case string:
+			if v == "" {
+				return float64(0), nil
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("'%s' is not a number", v)
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("'%v' is not a number", value)
+	case "choice":
+		v := fmt.Sprint(value)
+		for _, option := range input.Options {
+			if v == option {
+				return v, nil
+			}
+		}
+		if v == "" && !input.Required {
+			return v, nil
+		}
+		return nil, fmt.Errorf("'%s' is not one of the options [%s]", v, strings.Join(input.Options, ", "))
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
@@ -0,0 +1,102 @@
+package jobparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+func TestGetInputs(t *testing.T) {
+	workflow, err := model.ReadWorkflow(strings.NewReader(`
+name: test
+on:
+  workflow_dispatch:
+    inputs:
+      environment:
+        type: choice
+        options: [staging, production]
+        default: staging
+      dry-run:
+        type: boolean
+      retries:
+        type: number
+        default: "3"
+  workflow_call:
+    inputs:
+      version:
+        type: string
+        required: true
+jobs:
+  job1:
+    runs-on: linux
+    steps:
+      - run: uname -a
+`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		eventName string
+		values    map[string]interface{}
+		want      map[string]interface{}
+		wantErr   string
+	}{
+		{
+			name:      "defaults",
+			eventName: "workflow_dispatch",
+			values:    map[string]interface{}{},
+			want:      map[string]interface{}{"environment": "staging", "dry-run": false, "retries": float64(3)},
+		},
+		{
+			name:      "coercion",
+			eventName: "workflow_dispatch",
+			values:    map[string]interface{}{"environment": "production", "dry-run": "true", "retries": "1.5", "extra": "value"},
+			want:      map[string]interface{}{"environment": "production", "dry-run": true, "retries": 1.5, "extra": "value"},
+		},
+		{
+			name:      "invalid choice",
+			eventName: "workflow_dispatch",
+			values:    map[string]interface{}{"environment": "development"},
+			wantErr:   "input 'environment': 'development' is not one of the options [staging, production]",
+		},
+		{
+			name:      "invalid boolean",
+			eventName: "workflow_dispatch",
+			values:    map[string]interface{}{"dry-run": "yes"},
+			wantErr:   "input 'dry-run': 'yes' is not a boolean",
+		},
+		{
+			name:      "invalid number",
+			eventName: "workflow_dispatch",
+			values:    map[string]interface{}{"retries": "many"},
+			wantErr:   "input 'retries': 'many' is not a number",
+		},
+		{
+			name:      "workflow_call",
+			eventName: "workflow_call",
+			values:    map[string]interface{}{"version": 2},
+			want:      map[string]interface{}{"version": "2"},
+		},
+		{
+			name:      "missing required",
+			eventName: "workflow_call",
+			values:    map[string]interface{}{},
+			wantErr:   "input 'version' is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getInputs(workflow, tt.eventName, tt.values)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
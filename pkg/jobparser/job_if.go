@@ -0,0 +1,139 @@
+package jobparser
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/rhysd/actionlint"
+
+	"github.com/nektos/act/pkg/exprparser"
+	"github.com/nektos/act/pkg/model"
+)
+
+// JobIfResult is the result of evaluating `jobs.<id>.if` on the server
+type JobIfResult int
+
+const (
+	// JobIfUndecided means the expression can only be evaluated at runtime by the runner
+	JobIfUndecided JobIfResult = iota
+	// JobIfRun means the job has to be scheduled
+	JobIfRun
+	// JobIfSkip means the job is skipped without being scheduled
+	JobIfSkip
+)
+
+func (r JobIfResult) String() string {
+	switch r {
+	case JobIfRun:
+		return "run"
+	case JobIfSkip:
+		return "skip"
+	}
+	return "undecided"
+}
+
+// EvaluateJobIf evaluates `jobs.<id>.if` of the workflow with `success()` as the default status check,
+// the results of the needs are passed with WithJobResults.
+// The expression is left to the runner if it refers to contexts which are not available on the server,
+// or if the results of the needs are not known yet.
+func EvaluateJobIf(content []byte, jobID string, options ...ParseOption) (JobIfResult, error) {
+	origin, err := model.ReadWorkflow(bytes.NewReader(content))
+	if err != nil {
+		return JobIfUndecided, fmt.Errorf("model.ReadWorkflow: %w", err)
+	}
+
+	pc := &parseContext{}
+	for _, o := range options {
+		o(pc)
+	}
+	inputs, err := pc.getInputs(origin)
+	if err != nil {
+		return JobIfUndecided, err
+	}
+
+	job := origin.GetJob(jobID)
+	if job == nil {
+		return JobIfUndecided, fmt.Errorf("job '%s' not found", jobID)
+	}
+	return evaluateJobIf(origin, jobID, job, pc, pc.getJobResults(origin), inputs)
+}
+
+func evaluateJobIf(origin *model.Workflow, jobID string, job *model.Job, pc *parseContext, results map[string]*JobResult, inputs map[string]interface{}) (JobIfResult, error) {
+	expr, err := rewriteSubExpression(job.If.Value, false)
+	if err != nil {
+		return JobIfUndecided, err
+	}
+	if !pc.canEvaluateJobIf(origin, job, expr, inputs) {
+		return JobIfUndecided, nil
+	}
+
+	evaluator := NewExpressionEvaluator(NewInterpeterWithInputs(jobID, job, nil, pc.gitContext, results, pc.vars, inputs))
+	evaluated, err := evaluator.evaluate(expr, exprparser.DefaultStatusCheckSuccess)
+	if err != nil {
+		return JobIfUndecided, fmt.Errorf("  ❌  Error in if-expression: \"if: %s\" (%w)", job.If.Value, err)
+	}
+	if exprparser.IsTruthy(evaluated) {
+		return JobIfRun, nil
+	}
+	return JobIfSkip, nil
+}
+
+// canEvaluateJobIf returns true if the expression only refers to the contexts available on the server,
+// which are github, needs, vars and inputs, and the results of all the needs of the job are known
+func (c *parseContext) canEvaluateJobIf(origin *model.Workflow, job *model.Job, expr string, inputs map[string]interface{}) bool {
+	if !c.hasResultsOfNeeds(origin, job, map[string]bool{}) {
+		return false
+	}
+	if strings.TrimSpace(expr) == "" {
+		return true
+	}
+
+	exprNode, parseErr := actionlint.NewExprParser().Parse(actionlint.NewExprLexer(strings.TrimPrefix(expr, "${{") + "}}"))
+	if parseErr != nil {
+		// the runner reports the invalid expression
+		return false
+	}
+
+	available := true
+	actionlint.VisitExprNode(exprNode, func(node, _ actionlint.ExprNode, entering bool) {
+		if !entering {
+			return
+		}
+		switch node := node.(type) {
+		case *actionlint.VariableNode:
+			switch strings.ToLower(node.Name) {
+			case "needs", "vars":
+			case "github", "gitea":
+				available = available && c.gitContext != nil
+			case "inputs":
+				available = available && inputs != nil
+			default:
+				available = false
+			}
+		case *actionlint.FuncCallNode:
+			switch strings.ToLower(node.Callee) {
+			case "cancelled", "hashfiles":
+				available = false
+			}
+		}
+	})
+	return available
+}
+
+// hasResultsOfNeeds returns true if the results of the needs of the job and their needs are known
+func (c *parseContext) hasResultsOfNeeds(origin *model.Workflow, job *model.Job, visited map[string]bool) bool {
+	for _, need := range job.Needs() {
+		if visited[need] {
+			continue
+		}
+		visited[need] = true
+		if c.jobResults[need] == "" {
+			return false
+		}
+		if needJob := origin.GetJob(need); needJob != nil && !c.hasResultsOfNeeds(origin, needJob, visited) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package jobparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+func TestEvaluateJobIf(t *testing.T) {
+	content := []byte(`
+name: test
+on:
+  workflow_dispatch:
+    inputs:
+      deploy:
+        type: boolean
+jobs:
+  build:
+    runs-on: linux
+    steps:
+      - run: make
+  test:
+    needs: build
+    runs-on: linux
+    steps:
+      - run: make test
+  deploy:
+    needs: test
+    if: ${{ inputs.deploy && github.ref == 'refs/heads/main' }}
+    runs-on: linux
+    steps:
+      - run: make deploy
+  report:
+    needs: build
+    if: always() && vars.REPORT == 'true'
+    runs-on: linux
+    steps:
+      - run: make report
+  notify:
+    if: failure() || env.NOTIFY == 'true'
+    runs-on: linux
+    steps:
+      - run: make notify
+  cleanup:
+    needs: build
+    if: cancelled()
+    runs-on: linux
+    steps:
+      - run: make cleanup
+`)
+	gitContext := &model.GithubContext{Ref: "refs/heads/main", EventName: "workflow_dispatch"}
+
+	tests := []struct {
+		name    string
+		jobID   string
+		options []ParseOption
+		want    JobIfResult
+	}{
+		{
+			name:  "without needs",
+			jobID: "build",
+			want:  JobIfRun,
+		},
+		{
+			name:  "unknown needs result",
+			jobID: "test",
+			want:  JobIfUndecided,
+		},
+		{
+			name:    "needs failed",
+			jobID:   "test",
+			options: []ParseOption{WithJobResults(map[string]string{"build": "failure"})},
+			want:    JobIfSkip,
+		},
+		{
+			name:    "unknown transitive needs result",
+			jobID:   "deploy",
+			options: []ParseOption{WithJobResults(map[string]string{"test": "success"}), WithGitContext(gitContext)},
+			want:    JobIfUndecided,
+		},
+		{
+			name:  "without inputs",
+			jobID: "deploy",
+			options: []ParseOption{
+				WithJobResults(map[string]string{"build": "success", "test": "success"}),
+				WithGitContext(gitContext),
+			},
+			want: JobIfUndecided,
+		},
+		{
+			name:  "inputs and github",
+			jobID: "deploy",
+			options: []ParseOption{
+				WithJobResults(map[string]string{"build": "success", "test": "success"}),
+				WithGitContext(gitContext),
+				WithInputs(map[string]interface{}{"deploy": "true"}),
+			},
+			want: JobIfRun,
+		},
+		{
+			name:  "inputs false",
+			jobID: "deploy",
+			options: []ParseOption{
+				WithJobResults(map[string]string{"build": "success", "test": "success"}),
+				WithGitContext(gitContext),
+				WithInputs(map[string]interface{}{"deploy": false}),
+			},
+			want: JobIfSkip,
+		},
+		{
+			name:  "always with vars",
+			jobID: "report",
+			options: []ParseOption{
+				WithJobResults(map[string]string{"build": "failure"}),
+				WithVars(map[string]string{"REPORT": "true"}),
+			},
+			want: JobIfRun,
+		},
+		{
+			name:  "runtime context",
+			jobID: "notify",
+			want:  JobIfUndecided,
+		},
+		{
+			name:    "cancelled",
+			jobID:   "cleanup",
+			options: []ParseOption{WithJobResults(map[string]string{"build": "success"})},
+			want:    JobIfUndecided,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvaluateJobIf(content, tt.jobID, tt.options...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.String(), got.String())
+		})
+	}
+
+	_, err := EvaluateJobIf(content, "missing")
+	assert.EqualError(t, err, "job 'missing' not found")
+}
@@ -12,6 +12,14 @@ import (
 )
 
 func Parse(content []byte, options ...ParseOption) ([]*SingleWorkflow, error) {
+	pc := &parseContext{}
+	for _, o := range options {
+		o(pc)
+	}
+	return parse(content, pc)
+}
+
+func parse(content []byte, pc *parseContext) ([]*SingleWorkflow, error) {
 	origin, err := model.ReadWorkflow(bytes.NewReader(content))
 	if err != nil {
 		return nil, fmt.Errorf("model.ReadWorkflow: %w", err)
@@ -21,19 +29,18 @@ func Parse(content []byte, options ...ParseOption) ([]*SingleWorkflow, error) {
 	if err := yaml.Unmarshal(content, workflow); err != nil {
 		return nil, fmt.Errorf("yaml.Unmarshal: %w", err)
 	}
-
-	pc := &parseContext{}
-	for _, o := range options {
-		o(pc)
+	if workflow.RawOn, err = scopedNode(workflow.RawOn); err != nil {
+		return nil, err
 	}
-	results := map[string]*JobResult{}
-	for id, job := range origin.Jobs {
-		results[id] = &JobResult{
-			Needs:   job.Needs(),
-			Result:  pc.jobResults[id],
-			Outputs: nil, // not supported yet
-		}
+	if workflow.RawConcurrency, err = scopedNode(workflow.RawConcurrency); err != nil {
+		return nil, err
+	}
+
+	inputs, err := pc.getInputs(origin)
+	if err != nil {
+		return nil, err
 	}
+	results := pc.getJobResults(origin)
 
 	var ret []*SingleWorkflow
 	ids, jobs, err := workflow.jobs()
@@ -42,30 +49,50 @@ func Parse(content []byte, options ...ParseOption) ([]*SingleWorkflow, error) {
 	}
 	for i, id := range ids {
 		job := jobs[i]
-		matricxes, err := getMatrixes(origin.GetJob(id))
+		var evaluator *ExpressionEvaluator
+		if pc.evaluatesMatrix(origin.GetJob(id)) {
+			evaluator = NewExpressionEvaluator(NewInterpeterWithInputs(id, origin.GetJob(id), nil, pc.gitContext, results, pc.vars, inputs))
+		}
+		matricxes, err := getMatrixes(origin.GetJob(id), evaluator)
 		if err != nil {
 			return nil, fmt.Errorf("getMatrixes: %w", err)
 		}
-		for _, matrix := range matricxes {
+		for i, matrix := range matricxes {
 			job := job.Clone()
 			if job.Name == "" {
 				job.Name = id
 			}
 			job.Strategy.RawMatrix = encodeMatrix(matrix)
-			evaluator := NewExpressionEvaluator(NewInterpeter(id, origin.GetJob(id), matrix, pc.gitContext, results, pc.vars))
+			evaluator := NewExpressionEvaluator(NewInterpeterWithInputs(id, origin.GetJob(id), matrix, pc.gitContext, results, pc.vars, inputs))
 			job.Name = nameWithMatrix(job.Name, matrix, evaluator)
-			runsOn := origin.GetJob(id).RunsOn()
-			for i, v := range runsOn {
-				runsOn[i] = evaluator.Interpolate(v)
+			runsOn := origin.GetJob(id).RunsOnSpec()
+			runsOn.Group = evaluator.Interpolate(runsOn.Group)
+			for i, v := range runsOn.Labels {
+				runsOn.Labels[i] = evaluator.Interpolate(v)
+			}
+			if pc.expandsReusableWorkflow(origin.GetJob(id)) {
+				// the jobs called by every permutation of the matrix are namespaced by it
+				prefix := id
+				if len(matricxes) > 1 {
+					prefix = fmt.Sprintf("%s.%d", id, i+1)
+				}
+				expanded, err := pc.expandReusableWorkflow(workflow, id, prefix, job, origin.GetJob(id), evaluator)
+				if err != nil {
+					return nil, err
+				}
+				ret = append(ret, expanded...)
+				continue
 			}
 			job.RawRunsOn = encodeRunsOn(runsOn)
 			swf := &SingleWorkflow{
-				Name:     workflow.Name,
-				RawOn:    workflow.RawOn,
-				Env:      workflow.Env,
-				Defaults: workflow.Defaults,
+				Name:           workflow.Name,
+				RawOn:          workflow.RawOn,
+				Env:            workflow.Env,
+				Defaults:       workflow.Defaults,
+				RawConcurrency: workflow.RawConcurrency,
 			}
-			if err := swf.SetJob(id, job); err != nil {
+			key, node := workflow.jobNode(id)
+			if err := swf.setJob(id, job, key, node); err != nil {
 				return nil, fmt.Errorf("SetJob: %w", err)
 			}
 			ret = append(ret, swf)
@@ -80,6 +107,22 @@ func WithJobResults(results map[string]string) ParseOption {
 	}
 }
 
+// WithJobOutputs passes the outputs of the finished jobs, which can be used by the dependent jobs
+// in expressions of `strategy.matrix` and `runs-on`
+func WithJobOutputs(outputs map[string]map[string]string) ParseOption {
+	return func(c *parseContext) {
+		c.jobOutputs = outputs
+	}
+}
+
+// WithInputs passes the inputs of `workflow_dispatch` or `workflow_call`, the defaults and types
+// of the inputs declared in the workflow are applied to them
+func WithInputs(inputs map[string]interface{}) ParseOption {
+	return func(c *parseContext) {
+		c.inputs = inputs
+	}
+}
+
 func WithGitContext(context *model.GithubContext) ParseOption {
 	return func(c *parseContext) {
 		c.gitContext = context
@@ -94,13 +137,73 @@ func WithVars(vars map[string]string) ParseOption {
 
 type parseContext struct {
 	jobResults map[string]string
+	jobOutputs map[string]map[string]string
 	gitContext *model.GithubContext
 	vars       map[string]string
+	inputs     map[string]interface{}
+
+	workflowResolver WorkflowResolver
+	// callChain is the `uses` of the workflows which are being expanded, from the outermost one
+	callChain []string
 }
 
 type ParseOption func(c *parseContext)
 
-func getMatrixes(job *model.Job) ([]map[string]interface{}, error) {
+// getInputs returns the inputs passed with WithInputs, or nil if there are none
+func (c *parseContext) getInputs(origin *model.Workflow) (map[string]interface{}, error) {
+	if c.inputs == nil {
+		return nil, nil
+	}
+	eventName := ""
+	if c.gitContext != nil {
+		eventName = c.gitContext.EventName
+	}
+	inputs, err := getInputs(origin, eventName, c.inputs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inputs: %w", err)
+	}
+	return inputs, nil
+}
+
+func (c *parseContext) getJobResults(origin *model.Workflow) map[string]*JobResult {
+	results := map[string]*JobResult{}
+	for id, job := range origin.Jobs {
+		results[id] = &JobResult{
+			Needs:   job.Needs(),
+			Result:  c.jobResults[id],
+			Outputs: c.jobOutputs[id],
+		}
+	}
+	return results
+}
+
+// evaluatesMatrix returns true if the matrix of the job is evaluated while parsing, which is only done
+// if outputs or inputs have been passed and the outputs of all the needs of the job are known
+func (c *parseContext) evaluatesMatrix(job *model.Job) bool {
+	if c.jobOutputs == nil && c.inputs == nil {
+		return false
+	}
+	return c.hasOutputsOfNeeds(job)
+}
+
+// hasOutputsOfNeeds returns true if the outputs of all the needs of the job have been passed,
+// otherwise the expressions of the job which refer to them can not be evaluated yet
+func (c *parseContext) hasOutputsOfNeeds(job *model.Job) bool {
+	for _, need := range job.Needs() {
+		if _, ok := c.jobOutputs[need]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// getMatrixes returns the matrixes of the job, the matrix is evaluated with the evaluator first if not nil
+func getMatrixes(job *model.Job, evaluator *ExpressionEvaluator) ([]map[string]interface{}, error) {
+	if evaluator != nil && job.Strategy != nil {
+		if err := evaluator.EvaluateYamlNode(&job.Strategy.RawMatrix); err != nil {
+			return nil, fmt.Errorf("evaluate matrix: %w", err)
+		}
+	}
 	ret, err := job.GetMatrixes()
 	if err != nil {
 		return nil, fmt.Errorf("GetMatrixes: %w", err)
@@ -124,12 +227,24 @@ func encodeMatrix(matrix map[string]interface{}) yaml.Node {
 	return node
 }
 
-func encodeRunsOn(runsOn []string) yaml.Node {
+// encodeRunsOn encodes `runs-on`, the group is kept to match the job against the groups of the runners
+func encodeRunsOn(runsOn *model.RunsOn) yaml.Node {
 	node := yaml.Node{}
-	if len(runsOn) == 1 {
-		_ = node.Encode(runsOn[0])
+	var labels interface{} = runsOn.Labels
+	if len(runsOn.Labels) == 1 {
+		labels = runsOn.Labels[0]
+	}
+	if runsOn.Group != "" {
+		value := struct {
+			Group  string      `yaml:"group"`
+			Labels interface{} `yaml:"labels,omitempty"`
+		}{runsOn.Group, labels}
+		if len(runsOn.Labels) == 0 {
+			value.Labels = nil
+		}
+		_ = node.Encode(value)
 	} else {
-		_ = node.Encode(runsOn)
+		_ = node.Encode(labels)
 	}
 	return node
 }
@@ -1,6 +1,7 @@
 package jobparser
 
 import (
+	"path"
 	"strings"
 	"testing"
 
@@ -52,6 +53,53 @@ func TestParse(t *testing.T) {
 			options: nil,
 			wantErr: false,
 		},
+		{
+			name:    "has_concurrency",
+			options: nil,
+			wantErr: false,
+		},
+		{
+			name: "has_needs_outputs",
+			options: []ParseOption{
+				WithJobResults(map[string]string{"setup": "success"}),
+				WithJobOutputs(map[string]map[string]string{
+					"setup": {
+						"matrix": `{"version":["1.20","1.21"]}`,
+						"runner": "linux-arm64",
+					},
+				}),
+			},
+			wantErr: false,
+		},
+		{
+			// the matrix is only evaluated while parsing if outputs or inputs are passed
+			name:    "has_matrix_expression",
+			options: nil,
+			wantErr: false,
+		},
+		{
+			name: "has_inputs",
+			options: []ParseOption{
+				WithInputs(map[string]interface{}{"runner": "windows", "debug": "true"}),
+			},
+			wantErr: false,
+		},
+		{
+			name:    "has_anchors",
+			options: nil,
+			wantErr: false,
+		},
+		{
+			name: "has_reusable_workflow",
+			options: []ParseOption{
+				WithJobResults(map[string]string{"prepare": "success"}),
+				WithJobOutputs(map[string]map[string]string{"prepare": {"version": "1.0.0"}}),
+				WithWorkflowResolver(func(uses string) ([]byte, error) {
+					return testdata.ReadFile("testdata/reusable_workflow_" + strings.TrimSuffix(path.Base(uses), ".yml") + ".yaml")
+				}),
+			},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
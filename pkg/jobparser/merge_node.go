@@ -0,0 +1,140 @@
+package jobparser
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+// scopedNode returns the node with the aliases resolved whose anchors are defined outside of it,
+// so it can be marshalled on its own
+func scopedNode(node yaml.Node) (yaml.Node, error) {
+	if node.Kind == 0 {
+		return node, nil
+	}
+	resolved, err := model.ResolveAliases(&node)
+	if err != nil {
+		return node, err
+	}
+	merged, _ := newNodeMerger(&node).merge(&node, resolved)
+	return *merged, nil
+}
+
+// nodeMerger merges an encoded node into the node it has been decoded from, the parts of the original node
+// which have not been changed are kept with their comments, styles, anchors and positions
+type nodeMerger struct {
+	// the anchors which are defined in the original node and can still be referred to by aliases
+	anchors map[*yaml.Node]bool
+}
+
+func newNodeMerger(origin *yaml.Node) *nodeMerger {
+	m := &nodeMerger{anchors: map[*yaml.Node]bool{}}
+	m.collectAnchors(origin)
+	return m
+}
+
+func (m *nodeMerger) collectAnchors(node *yaml.Node) {
+	if node == nil || node.Kind == yaml.AliasNode {
+		return
+	}
+	if node.Anchor != "" {
+		m.anchors[node] = true
+	}
+	for _, n := range node.Content {
+		m.collectAnchors(n)
+	}
+}
+
+// merge returns the merged node and whether it differs from the original node
+func (m *nodeMerger) merge(origin, encoded *yaml.Node) (*yaml.Node, bool) {
+	if origin.Kind == yaml.AliasNode {
+		merged, changed := m.merge(origin.Alias, encoded)
+		if !changed && m.anchors[origin.Alias] {
+			return origin, false
+		}
+		// the anchor is defined outside of the node or its content has been changed
+		node := *merged
+		node.Anchor = ""
+		return &node, changed
+	}
+
+	var content []*yaml.Node
+	changed := false
+	switch {
+	case origin.Kind == yaml.ScalarNode && encoded.Kind == yaml.ScalarNode:
+		if origin.Value == encoded.Value {
+			return origin, false
+		}
+		changed = true
+	case origin.Kind == yaml.SequenceNode && encoded.Kind == yaml.SequenceNode && len(origin.Content) == len(encoded.Content):
+		content = make([]*yaml.Node, len(origin.Content))
+		for i := range origin.Content {
+			var c bool
+			content[i], c = m.merge(origin.Content[i], encoded.Content[i])
+			changed = changed || c
+		}
+	case origin.Kind == yaml.MappingNode && encoded.Kind == yaml.MappingNode:
+		content, changed = m.mergeMapping(origin, encoded)
+	default:
+		changed = true
+	}
+
+	if changed {
+		delete(m.anchors, origin)
+		if content == nil {
+			return encoded, true
+		}
+	}
+	node := *origin
+	node.Content = content
+	if changed {
+		node.Anchor = ""
+	}
+	return &node, changed
+}
+
+// mergeMapping keeps the order of the keys of the original mapping, the removed keys are dropped
+// and the new keys are inserted after the key they follow in the encoded mapping
+func (m *nodeMerger) mergeMapping(origin, encoded *yaml.Node) ([]*yaml.Node, bool) {
+	var keys []string
+	values := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(encoded.Content); i += 2 {
+		keys = append(keys, encoded.Content[i].Value)
+		values[encoded.Content[i].Value] = encoded.Content[i+1]
+	}
+
+	content := make([]*yaml.Node, 0, len(encoded.Content))
+	changed := false
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(origin.Content); i += 2 {
+		key := origin.Content[i]
+		value, ok := values[key.Value]
+		if !ok || key.Kind != yaml.ScalarNode || seen[key.Value] {
+			changed = true
+			continue
+		}
+		seen[key.Value] = true
+		merged, c := m.merge(origin.Content[i+1], value)
+		changed = changed || c
+		content = append(content, key, merged)
+	}
+
+	for i, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		changed = true
+		index := 0
+		if i > 0 {
+			for j := 0; j+1 < len(content); j += 2 {
+				if content[j].Value == keys[i-1] {
+					index = j + 2
+					break
+				}
+			}
+		}
+		content = append(content[:index], append([]*yaml.Node{encoded.Content[2*i], values[key]}, content[index:]...)...)
+	}
+	return content, changed
+}
@@ -9,11 +9,16 @@ import (
 
 // SingleWorkflow is a workflow with single job and single matrix
 type SingleWorkflow struct {
-	Name     string            `yaml:"name,omitempty"`
-	RawOn    yaml.Node         `yaml:"on,omitempty"`
-	Env      map[string]string `yaml:"env,omitempty"`
-	RawJobs  yaml.Node         `yaml:"jobs,omitempty"`
-	Defaults Defaults          `yaml:"defaults,omitempty"`
+	Name           string            `yaml:"name,omitempty"`
+	RawOn          yaml.Node         `yaml:"on,omitempty"`
+	Env            map[string]string `yaml:"env,omitempty"`
+	RawJobs        yaml.Node         `yaml:"jobs,omitempty"`
+	Defaults       Defaults          `yaml:"defaults,omitempty"`
+	RawConcurrency yaml.Node         `yaml:"concurrency,omitempty"`
+
+	// set by WithWorkflowResolver
+	CallerJobID string `yaml:"-"` // the id of the caller job the job has been expanded from
+	Expanded    bool   `yaml:"-"` // the job calls a reusable workflow whose jobs have been expanded, it has no steps and concludes with them
 }
 
 func (w *SingleWorkflow) Job() (string, *Job) {
@@ -43,45 +48,82 @@ func (w *SingleWorkflow) jobs() ([]string, []*Job, error) {
 	return ids, jobs, nil
 }
 
+// SetJob sets the job of the workflow, the parts of the job which are set already and have not been changed
+// keep their comments, key order, anchors and positions in the original workflow file
 func (w *SingleWorkflow) SetJob(id string, job *Job) error {
+	key, origin := w.jobNode(id)
+	return w.setJob(id, job, key, origin)
+}
+
+func (w *SingleWorkflow) setJob(id string, job *Job, key, origin *yaml.Node) error {
 	m := map[string]*Job{
 		id: job,
 	}
-	out, err := yaml.Marshal(m)
-	if err != nil {
-		return err
-	}
 	node := yaml.Node{}
-	if err := yaml.Unmarshal(out, &node); err != nil {
+	if err := node.Encode(m); err != nil {
 		return err
 	}
-	if len(node.Content) != 1 || node.Content[0].Kind != yaml.MappingNode {
-		return fmt.Errorf("can not set job: %q", out)
+	if node.Kind != yaml.MappingNode || len(node.Content) != 2 {
+		return fmt.Errorf("can not set job: %q", id)
+	}
+	if key != nil {
+		node.Content[0] = key
+	}
+	if origin != nil {
+		node.Content[1], _ = newNodeMerger(origin).merge(origin, node.Content[1])
 	}
-	w.RawJobs = *node.Content[0]
+	w.RawJobs = node
 	return nil
 }
 
+// jobNode returns the key and the value nodes of the job in RawJobs
+func (w *SingleWorkflow) jobNode(id string) (*yaml.Node, *yaml.Node) {
+	if w.RawJobs.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(w.RawJobs.Content); i += 2 {
+		if key := w.RawJobs.Content[i]; key.Value == id {
+			return key, w.RawJobs.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// SelectRunner returns the runner which matches `runs-on` of the job best,
+// model.NoRunnerError is returned if no runner can satisfy it
+func (w *SingleWorkflow) SelectRunner(runners []*model.RunnerLabels) (*model.RunnerLabels, error) {
+	id, job := w.Job()
+	if job == nil {
+		return nil, fmt.Errorf("the workflow has no job")
+	}
+	if job.Uses != "" && job.RawRunsOn.Kind == 0 {
+		return nil, fmt.Errorf("job '%s' calls a reusable workflow and does not run on a runner", id)
+	}
+	return model.SelectRunner(runners, job.RunsOnSpec())
+}
+
 func (w *SingleWorkflow) Marshal() ([]byte, error) {
 	return yaml.Marshal(w)
 }
 
 type Job struct {
-	Name           string                    `yaml:"name,omitempty"`
-	RawNeeds       yaml.Node                 `yaml:"needs,omitempty"`
-	RawRunsOn      yaml.Node                 `yaml:"runs-on,omitempty"`
-	Env            yaml.Node                 `yaml:"env,omitempty"`
-	If             yaml.Node                 `yaml:"if,omitempty"`
-	Steps          []*Step                   `yaml:"steps,omitempty"`
-	TimeoutMinutes string                    `yaml:"timeout-minutes,omitempty"`
-	Services       map[string]*ContainerSpec `yaml:"services,omitempty"`
-	Strategy       Strategy                  `yaml:"strategy,omitempty"`
-	RawContainer   yaml.Node                 `yaml:"container,omitempty"`
-	Defaults       Defaults                  `yaml:"defaults,omitempty"`
-	Outputs        map[string]string         `yaml:"outputs,omitempty"`
-	Uses           string                    `yaml:"uses,omitempty"`
-	With           map[string]interface{}    `yaml:"with,omitempty"`
-	RawSecrets     yaml.Node                 `yaml:"secrets,omitempty"`
+	Name               string                    `yaml:"name,omitempty"`
+	RawNeeds           yaml.Node                 `yaml:"needs,omitempty"`
+	RawRunsOn          yaml.Node                 `yaml:"runs-on,omitempty"`
+	Env                yaml.Node                 `yaml:"env,omitempty"`
+	If                 yaml.Node                 `yaml:"if,omitempty"`
+	Steps              []*Step                   `yaml:"steps,omitempty"`
+	TimeoutMinutes     string                    `yaml:"timeout-minutes,omitempty"`
+	Services           map[string]*ContainerSpec `yaml:"services,omitempty"`
+	Strategy           Strategy                  `yaml:"strategy,omitempty"`
+	RawContainer       yaml.Node                 `yaml:"container,omitempty"`
+	Defaults           Defaults                  `yaml:"defaults,omitempty"`
+	Outputs            map[string]string         `yaml:"outputs,omitempty"`
+	Uses               string                    `yaml:"uses,omitempty"`
+	With               map[string]interface{}    `yaml:"with,omitempty"`
+	RawSecrets         yaml.Node                 `yaml:"secrets,omitempty"`
+	RawConcurrency     yaml.Node                 `yaml:"concurrency,omitempty"`
+	RawContinueOnError string                    `yaml:"continue-on-error,omitempty"`
 }
 
 func (j *Job) Clone() *Job {
@@ -89,21 +131,23 @@ func (j *Job) Clone() *Job {
 		return nil
 	}
 	return &Job{
-		Name:           j.Name,
-		RawNeeds:       j.RawNeeds,
-		RawRunsOn:      j.RawRunsOn,
-		Env:            j.Env,
-		If:             j.If,
-		Steps:          j.Steps,
-		TimeoutMinutes: j.TimeoutMinutes,
-		Services:       j.Services,
-		Strategy:       j.Strategy,
-		RawContainer:   j.RawContainer,
-		Defaults:       j.Defaults,
-		Outputs:        j.Outputs,
-		Uses:           j.Uses,
-		With:           j.With,
-		RawSecrets:     j.RawSecrets,
+		Name:               j.Name,
+		RawNeeds:           j.RawNeeds,
+		RawRunsOn:          j.RawRunsOn,
+		Env:                j.Env,
+		If:                 j.If,
+		Steps:              j.Steps,
+		TimeoutMinutes:     j.TimeoutMinutes,
+		Services:           j.Services,
+		Strategy:           j.Strategy,
+		RawContainer:       j.RawContainer,
+		Defaults:           j.Defaults,
+		Outputs:            j.Outputs,
+		Uses:               j.Uses,
+		With:               j.With,
+		RawSecrets:         j.RawSecrets,
+		RawConcurrency:     j.RawConcurrency,
+		RawContinueOnError: j.RawContinueOnError,
 	}
 }
 
@@ -120,6 +164,11 @@ func (j *Job) RunsOn() []string {
 	return (&model.Job{RawRunsOn: j.RawRunsOn}).RunsOn()
 }
 
+// RunsOnSpec returns `runs-on` of the job without flattening the group into the labels
+func (j *Job) RunsOnSpec() *model.RunsOn {
+	return (&model.Job{RawRunsOn: j.RawRunsOn}).RunsOnSpec()
+}
+
 type Step struct {
 	ID               string            `yaml:"id,omitempty"`
 	If               yaml.Node         `yaml:"if,omitempty"`
@@ -190,7 +239,28 @@ func (evt *Event) Schedules() []map[string]string {
 	return evt.schedules
 }
 
+// ParseSchedules validates the `cron` of the schedules of the event
+func (evt *Event) ParseSchedules() ([]*model.Schedule, error) {
+	schedules := make([]*model.Schedule, 0, len(evt.schedules))
+	for _, s := range evt.schedules {
+		spec, ok := s["cron"]
+		if !ok {
+			continue
+		}
+		schedule, err := model.ParseSchedule(spec)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
 func ParseRawOn(rawOn *yaml.Node) ([]*Event, error) {
+	rawOn, err := model.ResolveAliases(rawOn)
+	if err != nil {
+		return nil, err
+	}
 	switch rawOn.Kind {
 	case yaml.ScalarNode:
 		var val string
@@ -301,6 +371,10 @@ func ParseRawOn(rawOn *yaml.Node) ([]*Event, error) {
 
 // parseMappingNode parse a mapping node and preserve order.
 func parseMappingNode[T any](node *yaml.Node) ([]string, []T, error) {
+	node, err := model.ResolveAliases(node)
+	if err != nil {
+		return nil, nil, err
+	}
 	if node.Kind != yaml.MappingNode {
 		return nil, nil, fmt.Errorf("input node is not a mapping node")
 	}
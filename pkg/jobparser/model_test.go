@@ -304,3 +304,45 @@ func TestParseMappingNode(t *testing.T) {
 		})
 	}
 }
+
+func TestEvent_ParseSchedules(t *testing.T) {
+	evt := &Event{
+		Name:      "schedule",
+		schedules: []map[string]string{{"cron": "30 2 * * *"}, {"cron": "*/15 * * * 1-5"}},
+	}
+	schedules, err := evt.ParseSchedules()
+	require.NoError(t, err)
+	require.Len(t, schedules, 2)
+	assert.Equal(t, "*/15 * * * 1-5", schedules[1].Cron)
+
+	evt.schedules = append(evt.schedules, map[string]string{"cron": "@daily"})
+	_, err = evt.ParseSchedules()
+	assert.Error(t, err)
+}
+
+func TestSingleWorkflow_SelectRunner(t *testing.T) {
+	swfs, err := Parse([]byte(`
+on: push
+jobs:
+  build:
+    runs-on:
+      group: large
+      labels: linux
+    steps:
+      - run: make
+`))
+	require.NoError(t, err)
+	require.Len(t, swfs, 1)
+
+	small, err := model.NewRunnerLabels("small", "linux:host")
+	require.NoError(t, err)
+	large, err := model.NewRunnerLabels("large", "linux:docker://node:16")
+	require.NoError(t, err)
+
+	runner, err := swfs[0].SelectRunner([]*model.RunnerLabels{small, large})
+	require.NoError(t, err)
+	assert.Same(t, large, runner)
+
+	_, err = swfs[0].SelectRunner([]*model.RunnerLabels{small})
+	assert.EqualError(t, err, "no runner can satisfy labels [linux] in group 'large'")
+}
@@ -0,0 +1,471 @@
+package jobparser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rhysd/actionlint"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+// WorkflowResolver returns the content of the workflow called by a job with `uses`,
+// which is either a local workflow like `./.github/workflows/x.yml`
+// or a remote one like `owner/repo/.github/workflows/x.yml@ref`
+type WorkflowResolver func(uses string) ([]byte, error)
+
+// WithWorkflowResolver expands the jobs calling reusable workflows, the jobs of the called workflows
+// are returned as SingleWorkflows with the id `<caller>.<job>`, so that they can be scheduled like any other job.
+// The jobs called by the permutations of a matrix caller are namespaced by the permutation, `<caller>.<n>.<job>`.
+// The caller job itself is kept without steps and depends on the expanded jobs, see SingleWorkflow.Expanded.
+// A caller whose `with` refers to the needs context is returned unexpanded, until the outputs of its needs are passed
+// with WithJobOutputs.
+func WithWorkflowResolver(resolver WorkflowResolver) ParseOption {
+	return func(c *parseContext) {
+		c.workflowResolver = resolver
+	}
+}
+
+// maxWorkflowLevels is the number of levels of workflows which GitHub connects, the caller and the called workflows
+const maxWorkflowLevels = 4
+
+// expandsReusableWorkflow returns true if the job calls a reusable workflow, which can be expanded already
+func (c *parseContext) expandsReusableWorkflow(job *model.Job) bool {
+	if c.workflowResolver == nil {
+		return false
+	}
+	jobType, _ := job.Type()
+	if jobType != model.JobTypeReusableWorkflowLocal && jobType != model.JobTypeReusableWorkflowRemote {
+		return false
+	}
+	if c.hasOutputsOfNeeds(job) {
+		return true
+	}
+	// the inputs of the called workflow are resolved when it is expanded, the outputs of the needs have to be known by then
+	for _, v := range job.With {
+		if s, ok := v.(string); ok && refersToContext(s, "needs") {
+			return false
+		}
+	}
+	return true
+}
+
+// expandReusableWorkflow returns the jobs of the workflow called by the caller job, followed by the caller job.
+// The expanded jobs are namespaced by the prefix, which is the id of the caller or of its matrix permutation.
+func (c *parseContext) expandReusableWorkflow(workflow *SingleWorkflow, callerID, prefix string, caller *Job, origin *model.Job, evaluator *ExpressionEvaluator) ([]*SingleWorkflow, error) {
+	callChain := append(append([]string{}, c.callChain...), origin.Uses)
+	for _, uses := range c.callChain {
+		if uses == origin.Uses {
+			return nil, fmt.Errorf("workflow '%s' of job '%s' calls itself: %s", origin.Uses, callerID, strings.Join(callChain, " -> "))
+		}
+	}
+	if len(callChain)+1 > maxWorkflowLevels {
+		return nil, fmt.Errorf("workflow '%s' of job '%s' is nested more than %d levels deep: %s", origin.Uses, callerID, maxWorkflowLevels, strings.Join(callChain, " -> "))
+	}
+
+	content, err := c.workflowResolver(origin.Uses)
+	if err != nil {
+		return nil, fmt.Errorf("resolve workflow '%s': %w", origin.Uses, err)
+	}
+	called, err := model.ReadWorkflow(strings.NewReader(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("model.ReadWorkflow '%s': %w", origin.Uses, err)
+	}
+
+	with := make(map[string]interface{}, len(origin.With))
+	for k, v := range origin.With {
+		if s, ok := v.(string); ok {
+			v = evaluator.Interpolate(s)
+		}
+		with[k] = v
+	}
+	inputs, err := getInputs(called, "workflow_call", with)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inputs of job '%s': %w", callerID, err)
+	}
+	secrets, err := callerSecrets(callerID, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	// namespace returns the id of the expanded job, the jobs expanded from a nested caller are namespaced by it already
+	namespace := func(id string) (string, bool) {
+		for calledID := range called.Jobs {
+			if strings.EqualFold(calledID, firstSegment(id)) {
+				return prefix + "." + calledID + strings.TrimPrefix(id, firstSegment(id)), true
+			}
+		}
+		return id, false
+	}
+
+	// the called jobs are parsed with the results and outputs of their namespaced ids
+	pc := &parseContext{
+		jobResults:       map[string]string{},
+		jobOutputs:       map[string]map[string]string{},
+		gitContext:       c.gitContext,
+		vars:             c.vars,
+		inputs:           inputs,
+		workflowResolver: c.workflowResolver,
+		callChain:        callChain,
+	}
+	for namespaced, result := range c.jobResults {
+		if id := strings.TrimPrefix(namespaced, prefix+"."); id != namespaced {
+			pc.jobResults[id] = result
+		}
+	}
+	for namespaced, outputs := range c.jobOutputs {
+		if id := strings.TrimPrefix(namespaced, prefix+"."); id != namespaced {
+			pc.jobOutputs[id] = outputs
+		}
+	}
+	expanded, err := parse(content, pc)
+	if err != nil {
+		return nil, fmt.Errorf("expand workflow '%s' of job '%s': %w", origin.Uses, callerID, err)
+	}
+
+	rewrite := func(expr string) string {
+		expr = rewriteContextReferences(expr, "needs", func(name string) (string, bool) {
+			namespaced, ok := namespace(name)
+			return fmt.Sprintf("needs['%s']", namespaced), ok
+		})
+		expr = rewriteContextReferences(expr, "inputs", func(name string) (string, bool) {
+			for input, value := range inputs {
+				if strings.EqualFold(input, name) {
+					return literalOf(value), true
+				}
+			}
+			return "", false
+		})
+		if secrets != nil {
+			expr = rewriteContextReferences(expr, "secrets", func(name string) (string, bool) {
+				if strings.EqualFold(name, "GITHUB_TOKEN") {
+					return "", false
+				}
+				if secret, ok := secrets[strings.ToLower(name)]; ok {
+					return "secrets." + secret, true
+				}
+				// secrets which are not passed by the caller are empty
+				return "''", true
+			})
+		}
+		return expr
+	}
+
+	ret := make([]*SingleWorkflow, 0, len(expanded)+1)
+	ids := make([]string, 0, len(expanded))
+	for _, swf := range expanded {
+		id, job := swf.Job()
+		namespaced, _ := namespace(id)
+		ids = append(ids, namespaced)
+		job.Name = caller.Name + " / " + job.Name
+		needs := make([]string, 0, len(job.Needs()))
+		for _, need := range job.Needs() {
+			need, _ = namespace(need)
+			needs = append(needs, need)
+		}
+		root := len(needs) == 0
+
+		jobNode := yaml.Node{}
+		if err := jobNode.Encode(job); err != nil {
+			return nil, err
+		}
+		rewriteExpressions(&jobNode, "", rewrite)
+		rewritten := &Job{}
+		if err := jobNode.Decode(rewritten); err != nil {
+			return nil, err
+		}
+		if root {
+			// the jobs of the called workflow wait for the needs of the caller and are run only if the caller is
+			needs = caller.Needs()
+			if caller.If.Value != "" {
+				rewritten.If = yaml.Node{
+					Kind:  yaml.ScalarNode,
+					Value: fmt.Sprintf("(%s) && (%s)", bareExpression(caller.If.Value), bareExpression(rewritten.If.Value)),
+				}
+			}
+		}
+		rewritten.RawNeeds = encodeNeeds(needs)
+
+		ret = append(ret, &SingleWorkflow{
+			Name:           workflow.Name,
+			RawOn:          workflow.RawOn,
+			Env:            swf.Env,
+			Defaults:       swf.Defaults,
+			RawConcurrency: swf.RawConcurrency,
+			CallerJobID:    callerPrefix(callerID, swf.CallerJobID),
+			Expanded:       swf.Expanded,
+		})
+		if err := ret[len(ret)-1].SetJob(namespaced, rewritten); err != nil {
+			return nil, fmt.Errorf("SetJob: %w", err)
+		}
+	}
+
+	// the caller concludes once the called jobs are done, the outputs of the called workflow are mapped
+	// from the `jobs` context to the `needs` context of the expanded jobs
+	ids = uniqueSorted(ids)
+	callerJob := &Job{
+		Name:           caller.Name,
+		RawNeeds:       encodeNeeds(append(caller.Needs(), ids...)),
+		If:             caller.If,
+		Strategy:       caller.Strategy,
+		RawConcurrency: caller.RawConcurrency,
+		Outputs:        map[string]string{},
+	}
+	for name, output := range called.WorkflowCallConfig().Outputs {
+		callerJob.Outputs[name] = rewriteExpression(output.Value, func(expr string) string {
+			return rewriteContextReferences(expr, "jobs", func(name string) (string, bool) {
+				namespaced, ok := namespace(name)
+				return fmt.Sprintf("needs['%s']", namespaced), ok
+			})
+		})
+	}
+	callerSwf := &SingleWorkflow{
+		Name:           workflow.Name,
+		RawOn:          workflow.RawOn,
+		Env:            workflow.Env,
+		Defaults:       workflow.Defaults,
+		RawConcurrency: workflow.RawConcurrency,
+		Expanded:       true,
+	}
+	if err := callerSwf.SetJob(callerID, callerJob); err != nil {
+		return nil, fmt.Errorf("SetJob: %w", err)
+	}
+	return append(ret, callerSwf), nil
+}
+
+// EvaluateOutputs evaluates the outputs of the job with the results and outputs of its needs,
+// the server concludes an expanded caller job with it, once the jobs of the called workflow are done
+func (w *SingleWorkflow) EvaluateOutputs(options ...ParseOption) (map[string]string, error) {
+	content, err := w.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	origin, err := model.ReadWorkflow(strings.NewReader(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("model.ReadWorkflow: %w", err)
+	}
+	pc := &parseContext{}
+	for _, o := range options {
+		o(pc)
+	}
+
+	id, _ := w.Job()
+	job := origin.GetJob(id)
+	if job == nil {
+		return nil, fmt.Errorf("job '%s' not found", id)
+	}
+	results := map[string]*JobResult{
+		id: {Needs: job.Needs()},
+	}
+	for _, need := range job.Needs() {
+		results[need] = &JobResult{
+			Result:  pc.jobResults[need],
+			Outputs: pc.jobOutputs[need],
+		}
+	}
+
+	evaluator := NewExpressionEvaluator(NewInterpeter(id, job, nil, pc.gitContext, results, pc.vars))
+	outputs := make(map[string]string, len(job.Outputs))
+	for name, value := range job.Outputs {
+		outputs[name] = evaluator.Interpolate(value)
+	}
+	return outputs, nil
+}
+
+// callerSecrets returns the secrets passed to the called workflow by name, or nil if the caller inherits the secrets
+func callerSecrets(callerID string, caller *model.Job) (map[string]string, error) {
+	if caller.InheritSecrets() {
+		return nil, nil
+	}
+	secretPattern := regexp.MustCompile(`^\s*\$\{\{\s*secrets\.([A-Za-z_][\w-]*)\s*}}\s*$`)
+	secrets := map[string]string{}
+	for name, value := range caller.Secrets() {
+		m := secretPattern.FindStringSubmatch(value)
+		if m == nil {
+			return nil, fmt.Errorf("secret '%s' of job '%s' can only be passed from a secret of the caller", name, callerID)
+		}
+		secrets[strings.ToLower(name)] = m[1]
+	}
+	return secrets, nil
+}
+
+func callerPrefix(callerID, nested string) string {
+	if nested == "" {
+		return callerID
+	}
+	return callerID + "." + nested
+}
+
+// bareExpression returns the expression without `${{ }}`, an empty expression is `success()`
+func bareExpression(expr string) string {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "${{") && strings.HasSuffix(expr, "}}") && strings.Count(expr, "${{") == 1 {
+		expr = strings.TrimSpace(expr[3 : len(expr)-2])
+	}
+	if expr == "" {
+		return "success()"
+	}
+	return expr
+}
+
+func uniqueSorted(values []string) []string {
+	sort.Strings(values)
+	ret := make([]string, 0, len(values))
+	for i, v := range values {
+		if i == 0 || values[i-1] != v {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func firstSegment(id string) string {
+	if i := strings.Index(id, "."); i >= 0 {
+		return id[:i]
+	}
+	return id
+}
+
+func encodeNeeds(needs []string) yaml.Node {
+	node := yaml.Node{}
+	if len(needs) == 0 {
+		return node
+	}
+	if len(needs) == 1 {
+		_ = node.Encode(needs[0])
+	} else {
+		_ = node.Encode(needs)
+	}
+	return node
+}
+
+// literalOf returns the value as a literal of an expression
+func literalOf(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return "null"
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", "''") + "'"
+	}
+}
+
+// rewriteExpressions rewrites the expressions in the values of the node, the value of `if` is an expression as a whole
+func rewriteExpressions(node *yaml.Node, key string, rewrite func(string) string) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if key == "if" && !strings.Contains(node.Value, "${{") {
+			node.Value = rewrite(node.Value)
+		} else {
+			node.Value = rewriteExpression(node.Value, rewrite)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			rewriteExpressions(node.Content[i+1], node.Content[i].Value, rewrite)
+		}
+	case yaml.SequenceNode:
+		for _, child := range node.Content {
+			rewriteExpressions(child, "", rewrite)
+		}
+	}
+}
+
+var expressionPattern = regexp.MustCompile(`\$\{\{(.*?)}}`)
+
+// rewriteExpression rewrites the `${{ }}` expressions in the value
+func rewriteExpression(value string, rewrite func(string) string) string {
+	return expressionPattern.ReplaceAllStringFunc(value, func(m string) string {
+		return "${{" + rewrite(m[3:len(m)-2]) + "}}"
+	})
+}
+
+// rewriteContextReferences replaces the references `context.name` and `context['name']` in the expression.
+// The references are found in the parsed expression, so string literals and properties of other objects are kept,
+// an invalid expression is returned as it is and reported by the runner.
+func rewriteContextReferences(expr string, context string, replace func(name string) (string, bool)) string {
+	tokens, _, lexErr := actionlint.LexExpression(expr + "}}")
+	if lexErr != nil {
+		return expr
+	}
+	exprNode, parseErr := actionlint.NewExprParser().Parse(actionlint.NewExprLexer(expr + "}}"))
+	if parseErr != nil {
+		return expr
+	}
+	tokenIndex := make(map[int]int, len(tokens))
+	for i, token := range tokens {
+		tokenIndex[token.Offset] = i
+	}
+
+	type reference struct {
+		start, end int
+		name       string
+	}
+	references := make([]reference, 0)
+	actionlint.VisitExprNode(exprNode, func(node, _ actionlint.ExprNode, entering bool) {
+		if !entering {
+			return
+		}
+		var receiver actionlint.ExprNode
+		switch node := node.(type) {
+		case *actionlint.ObjectDerefNode:
+			receiver = node.Receiver
+		case *actionlint.IndexAccessNode:
+			if _, ok := node.Index.(*actionlint.StringNode); !ok {
+				return
+			}
+			receiver = node.Operand
+		default:
+			return
+		}
+		variable, ok := receiver.(*actionlint.VariableNode)
+		if !ok || !strings.EqualFold(variable.Name, context) {
+			return
+		}
+		// the name is taken from the tokens, since the parser lowercases the names of properties
+		i := tokenIndex[variable.Token().Offset]
+		switch {
+		case i+2 < len(tokens) && tokens[i+1].Kind == actionlint.TokenKindDot && tokens[i+2].Kind == actionlint.TokenKindIdent:
+			name := tokens[i+2]
+			references = append(references, reference{variable.Token().Offset, name.Offset + len(name.Value), name.Value})
+		case i+3 < len(tokens) && tokens[i+1].Kind == actionlint.TokenKindLeftBracket && tokens[i+2].Kind == actionlint.TokenKindString && tokens[i+3].Kind == actionlint.TokenKindRightBracket:
+			name := node.(*actionlint.IndexAccessNode).Index.(*actionlint.StringNode).Value
+			references = append(references, reference{variable.Token().Offset, tokens[i+3].Offset + 1, name})
+		}
+	})
+
+	// the references are replaced from the end, so that the offsets of the others stay valid
+	sort.Slice(references, func(i, j int) bool {
+		return references[i].start > references[j].start
+	})
+	for _, ref := range references {
+		if replacement, ok := replace(ref.name); ok {
+			expr = expr[:ref.start] + replacement + expr[ref.end:]
+		}
+	}
+	return expr
+}
+
+// refersToContext returns true if an expression in the value refers to the context
+func refersToContext(value string, context string) bool {
+	refers := false
+	rewriteExpression(value, func(expr string) string {
+		exprNode, parseErr := actionlint.NewExprParser().Parse(actionlint.NewExprLexer(expr + "}}"))
+		if parseErr != nil {
+			return expr
+		}
+		actionlint.VisitExprNode(exprNode, func(node, _ actionlint.ExprNode, entering bool) {
+			if variable, ok := node.(*actionlint.VariableNode); entering && ok && strings.EqualFold(variable.Name, context) {
+				refers = true
+			}
+		})
+		return expr
+	})
+	return refers
+}
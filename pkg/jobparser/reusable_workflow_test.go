@@ -0,0 +1,177 @@
+package jobparser
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReusableWorkflow(t *testing.T) {
+	content := ReadTestdata(t, "has_reusable_workflow.in.yaml")
+	swfs, err := Parse(content,
+		WithJobResults(map[string]string{"prepare": "success"}),
+		WithJobOutputs(map[string]map[string]string{"prepare": {"version": "1.0.0"}}),
+		WithWorkflowResolver(func(uses string) ([]byte, error) {
+			return testdata.ReadFile("testdata/reusable_workflow_" + strings.TrimSuffix(path.Base(uses), ".yml") + ".yaml")
+		}),
+	)
+	require.NoError(t, err)
+	require.Len(t, swfs, 5)
+
+	for _, swf := range swfs[1:3] {
+		assert.Equal(t, "call", swf.CallerJobID)
+		assert.False(t, swf.Expanded)
+	}
+
+	caller := swfs[3]
+	id, _ := caller.Job()
+	assert.Equal(t, "call", id)
+	assert.True(t, caller.Expanded)
+
+	outputs, err := caller.EvaluateOutputs(
+		WithJobResults(map[string]string{"prepare": "success", "call.build": "success", "call.package": "success"}),
+		WithJobOutputs(map[string]map[string]string{"call.package": {"artifact": "app-1.0.0.tar.gz"}}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"artifact": "app-1.0.0.tar.gz"}, outputs)
+}
+
+func TestParseReusableWorkflowWithoutResolver(t *testing.T) {
+	content := ReadTestdata(t, "has_reusable_workflow.in.yaml")
+	swfs, err := Parse(content)
+	require.NoError(t, err)
+	for _, swf := range swfs {
+		assert.False(t, swf.Expanded)
+		assert.Empty(t, swf.CallerJobID)
+	}
+}
+
+func TestParseReusableWorkflowDeferred(t *testing.T) {
+	content := ReadTestdata(t, "has_reusable_workflow.in.yaml")
+	resolver := WithWorkflowResolver(func(uses string) ([]byte, error) {
+		return testdata.ReadFile("testdata/reusable_workflow_" + strings.TrimSuffix(path.Base(uses), ".yml") + ".yaml")
+	})
+
+	// the `with` of the caller refers to the outputs of prepare, which are not known when the workflow is triggered
+	swfs, err := Parse(content, resolver)
+	require.NoError(t, err)
+	require.Len(t, swfs, 3)
+	id, job := swfs[1].Job()
+	assert.Equal(t, "call", id)
+	assert.Equal(t, "./.github/workflows/build.yml", job.Uses)
+	assert.False(t, swfs[1].Expanded)
+
+	// the caller is expanded once the outputs are known
+	swfs, err = Parse(content, resolver,
+		WithJobResults(map[string]string{"prepare": "success"}),
+		WithJobOutputs(map[string]map[string]string{"prepare": {"version": "2.0.0"}}),
+	)
+	require.NoError(t, err)
+	require.Len(t, swfs, 5)
+	_, job = swfs[1].Job()
+	assert.Equal(t, "make VERSION=${{ '2.0.0' }} DEBUG=${{ true }}", job.Steps[0].Run)
+}
+
+func TestParseReusableWorkflowMatrix(t *testing.T) {
+	content := []byte(`
+name: test
+on: push
+jobs:
+  call:
+    strategy:
+      matrix:
+        version: ["1.0.0", "2.0.0"]
+    uses: ./.github/workflows/build.yml
+    with:
+      version: ${{ matrix.version }}
+`)
+	swfs, err := Parse(content, WithWorkflowResolver(func(uses string) ([]byte, error) {
+		return testdata.ReadFile("testdata/reusable_workflow_build.yaml")
+	}))
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(swfs))
+	for _, swf := range swfs {
+		id, _ := swf.Job()
+		ids = append(ids, id)
+	}
+	assert.Equal(t, []string{"call.1.build", "call.1.package", "call", "call.2.build", "call.2.package", "call"}, ids)
+
+	// every permutation of the caller depends on its own jobs
+	_, job := swfs[2].Job()
+	assert.Equal(t, []string{"call.1.build", "call.1.package"}, job.Needs())
+	assert.Equal(t, "${{ needs['call.1.package'].outputs.artifact }}", job.Outputs["artifact"])
+	_, job = swfs[4].Job()
+	assert.Equal(t, "needs['call.2.build'].result == 'success'", job.If.Value)
+	assert.Contains(t, job.Steps[0].Run, "app-${{ '2.0.0' }}.tar.gz")
+}
+
+func TestParseReusableWorkflowRecursive(t *testing.T) {
+	calling := func(uses string) []byte {
+		return []byte(`
+name: test
+on: [push, workflow_call]
+jobs:
+  call:
+    uses: ` + uses + `
+    with:
+      version: 1.0.0
+`)
+	}
+	workflows := map[string][]byte{
+		"./.github/workflows/self.yml": calling("./.github/workflows/self.yml"),
+		"./.github/workflows/a.yml":    calling("./.github/workflows/b.yml"),
+		"./.github/workflows/b.yml":    calling("./.github/workflows/a.yml"),
+		"./.github/workflows/1.yml":    calling("./.github/workflows/2.yml"),
+		"./.github/workflows/2.yml":    calling("./.github/workflows/3.yml"),
+		"./.github/workflows/3.yml":    calling("./.github/workflows/4.yml"),
+		"./.github/workflows/4.yml":    ReadTestdata(t, "reusable_workflow_build.yaml"),
+	}
+	resolver := WithWorkflowResolver(func(uses string) ([]byte, error) {
+		return workflows[uses], nil
+	})
+
+	_, err := Parse(workflows["./.github/workflows/self.yml"], resolver)
+	assert.ErrorContains(t, err, "calls itself")
+	_, err = Parse(workflows["./.github/workflows/a.yml"], resolver)
+	assert.ErrorContains(t, err, "calls itself: ./.github/workflows/b.yml -> ./.github/workflows/a.yml -> ./.github/workflows/b.yml")
+
+	// the caller and 3 levels of called workflows are connected, but not a 4th one
+	_, err = Parse(calling("./.github/workflows/2.yml"), resolver)
+	assert.NoError(t, err)
+	_, err = Parse(calling("./.github/workflows/1.yml"), resolver)
+	assert.ErrorContains(t, err, "more than 4 levels")
+}
+
+func TestRewriteContextReferences(t *testing.T) {
+	replace := func(name string) (string, bool) {
+		if name == "missing" {
+			return "", false
+		}
+		return "needs['call." + name + "']", true
+	}
+	tables := []struct {
+		expr string
+		want string
+	}{
+		{" needs.build.result ", " needs['call.build'].result "},
+		{"needs['build'].outputs.Version == 'x'", "needs['call.build'].outputs.Version == 'x'"},
+		{"NEEDS.Build.result", "needs['call.Build'].result"},
+		{"contains(needs.a.result, needs.b.result)", "contains(needs['call.a'].result, needs['call.b'].result)"},
+		{"needs.missing.result", "needs.missing.result"},
+		// string literals and properties of other objects are kept
+		{"format('needs.build is {0}', needs.build.result)", "format('needs.build is {0}', needs['call.build'].result)"},
+		{"github.event.needs.build", "github.event.needs.build"},
+		{"needs.*.result", "needs.*.result"},
+		// an invalid expression is kept as it is
+		{"needs.build.result ==", "needs.build.result =="},
+	}
+	for _, table := range tables {
+		t.Run(table.expr, func(t *testing.T) {
+			assert.Equal(t, table.want, rewriteContextReferences(table.expr, "needs", replace))
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package jobparser
+
+import (
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a position in the original workflow file
+type Position struct {
+	Line   int
+	Column int
+}
+
+// SourcePosition maps a position in the content returned by Marshal back to the original workflow file,
+// it returns false if the position belongs to a part of the workflow which has been generated, like the matrix of the job
+func (w *SingleWorkflow) SourcePosition(line, column int) (Position, bool) {
+	content, err := w.Marshal()
+	if err != nil {
+		return Position{}, false
+	}
+	node := yaml.Node{}
+	if err := yaml.Unmarshal(content, &node); err != nil || len(node.Content) == 0 {
+		return Position{}, false
+	}
+
+	lines := map[int]*lineMapping{}
+	root := node.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		switch root.Content[i].Value {
+		case "on":
+			mapLines(lines, root.Content[i+1], &w.RawOn)
+		case "jobs":
+			mapLines(lines, root.Content[i+1], &w.RawJobs)
+		case "concurrency":
+			mapLines(lines, root.Content[i+1], &w.RawConcurrency)
+		}
+	}
+
+	mapped := make([]int, 0, len(lines))
+	for l := range lines {
+		mapped = append(mapped, l)
+	}
+	sort.Ints(mapped)
+	index := sort.SearchInts(mapped, line+1) - 1
+	if index < 0 {
+		return Position{}, false
+	}
+	m := lines[mapped[index]]
+	if m == nil {
+		return Position{}, false
+	}
+	// the lines without nodes are the continuation lines of multi-line scalars
+	pos := Position{
+		Line:   m.source.Line + line - mapped[index],
+		Column: m.source.Column + column - m.column,
+	}
+	if pos.Column < 1 {
+		pos.Column = 1
+	}
+	return pos, true
+}
+
+// lineMapping maps the first scalar or alias node on a line of the marshalled content to its source,
+// the source is nil if the node has been generated
+type lineMapping struct {
+	column int
+	source *Position
+}
+
+// mapLines walks the marshalled node and the node it has been marshalled from together
+func mapLines(lines map[int]*lineMapping, marshalled, source *yaml.Node) {
+	// mappings and sequences start with their first item, which is mapped instead
+	if _, ok := lines[marshalled.Line]; !ok && len(marshalled.Content) == 0 {
+		var m *lineMapping
+		if source.Line > 0 {
+			m = &lineMapping{
+				column: marshalled.Column,
+				source: &Position{Line: source.Line, Column: source.Column},
+			}
+		}
+		lines[marshalled.Line] = m
+	}
+	if marshalled.Kind == yaml.AliasNode || marshalled.Kind != source.Kind || len(marshalled.Content) != len(source.Content) {
+		return
+	}
+	for i := range marshalled.Content {
+		mapLines(lines, marshalled.Content[i], source.Content[i])
+	}
+}
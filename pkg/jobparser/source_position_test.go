@@ -0,0 +1,115 @@
+package jobparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSingleWorkflow_SourcePosition(t *testing.T) {
+	content := []byte(`name: test
+on: push
+
+jobs:
+  # the first job
+  job1:
+    runs-on: linux
+    steps:
+      - run: echo job1
+
+  # the second job
+  job2:
+    needs: job1 # needs the first job
+    strategy:
+      matrix:
+        version: [1, 2]
+    runs-on: linux
+    env: &env
+      VERSION: ${{ matrix.version }}
+    steps:
+      - name: build
+        env: *env
+        run: |
+          make
+          make test
+`)
+	swfs, err := Parse(content)
+	require.NoError(t, err)
+	require.Len(t, swfs, 3)
+
+	marshalled, err := swfs[1].Marshal()
+	require.NoError(t, err)
+	assert.Contains(t, string(marshalled), "# the second job")
+	assert.Contains(t, string(marshalled), "# needs the first job")
+	assert.Contains(t, string(marshalled), "env: &env")
+	assert.Contains(t, string(marshalled), "env: *env")
+
+	lines := strings.Split(string(marshalled), "\n")
+	positionOf := func(s string) (int, int) {
+		for i, line := range lines {
+			if column := strings.Index(line, s); column >= 0 {
+				return i + 1, column + 1
+			}
+		}
+		t.Fatalf("%q not found in %s", s, marshalled)
+		return 0, 0
+	}
+
+	tests := []struct {
+		marshalled string
+		line       int
+		column     int
+		ok         bool
+	}{
+		{"job2:", 12, 3, true},
+		{"needs: job1", 13, 5, true},
+		{"runs-on: linux", 17, 5, true},
+		{"VERSION", 19, 7, true},
+		{"- name: build", 21, 7, true},
+		{"make test", 25, 11, true},
+		{`"on": push`, 2, 1, true},
+		// the matrix of the job has been generated
+		{"- 1", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.marshalled, func(t *testing.T) {
+			line, column := positionOf(tt.marshalled)
+			pos, ok := swfs[1].SourcePosition(line, column)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, Position{Line: tt.line, Column: tt.column}, pos)
+			}
+		})
+	}
+}
+
+func TestSingleWorkflow_SetJobPreservesSource(t *testing.T) {
+	content := []byte(`name: test
+on: push
+jobs:
+  job1:
+    runs-on: linux # the runner
+    needs: job0
+    steps:
+      - run: echo job1
+`)
+	swfs, err := Parse(content)
+	require.NoError(t, err)
+	require.Len(t, swfs, 1)
+
+	id, job := swfs[0].Job()
+	require.NoError(t, swfs[0].SetJob(id, job.EraseNeeds()))
+	marshalled, err := swfs[0].Marshal()
+	require.NoError(t, err)
+	assert.NotContains(t, string(marshalled), "needs")
+	assert.Contains(t, string(marshalled), "runs-on: linux # the runner")
+
+	// the marshalled job starts with its generated name
+	_, ok := swfs[0].SourcePosition(5, 9)
+	assert.False(t, ok)
+	pos, ok := swfs[0].SourcePosition(6, 9)
+	assert.True(t, ok)
+	assert.Equal(t, Position{Line: 5, Column: 5}, pos)
+}
@@ -0,0 +1,179 @@
+package jobparser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rhysd/actionlint"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+// Diagnostic is a problem found in a workflow file
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+	Kind    string // the name of the check which found the problem, like `syntax-check` or `expression`
+}
+
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s [%s]", d.File, d.Line, d.Column, d.Message, d.Kind)
+}
+
+// Validate checks the workflow before it is run, see ValidateFile
+func Validate(workflow []byte) []*Diagnostic {
+	return ValidateFile("", workflow)
+}
+
+// ValidateFile checks the syntax of the workflow and its expressions, such as the contexts which are not available
+// where they are used and `needs` of unknown jobs, the diagnostics are sorted by position
+func ValidateFile(file string, workflow []byte) []*Diagnostic {
+	content, position := resolveAnchors(workflow)
+	w, errs := actionlint.Parse(content)
+	if w != nil {
+		rules := []actionlint.Rule{
+			actionlint.NewRuleMatrix(),
+			actionlint.NewRuleCredentials(),
+			actionlint.NewRuleShellName(),
+			actionlint.NewRuleEvents(),
+			actionlint.NewRuleJobNeeds(),
+			actionlint.NewRuleEnvVar(),
+			actionlint.NewRuleID(),
+			actionlint.NewRuleGlob(),
+			actionlint.NewRulePermissions(),
+			actionlint.NewRuleExpression(actionlint.NewLocalActionsCache(nil, nil), actionlint.NewLocalReusableWorkflowCache(nil, "", nil)),
+			actionlint.NewRuleIfCond(),
+		}
+		visitor := actionlint.NewVisitor()
+		for _, rule := range rules {
+			visitor.AddPass(rule)
+		}
+		if err := visitor.Visit(w); err != nil {
+			errs = append(errs, &actionlint.Error{Message: err.Error(), Line: 1, Column: 1, Kind: "validate"})
+		}
+		for _, rule := range rules {
+			errs = append(errs, rule.Errs()...)
+		}
+	}
+	for _, err := range errs {
+		err.Line, err.Column = position(err.Line, err.Column)
+	}
+	sort.Stable(actionlint.ByErrorPosition(errs))
+
+	diagnostics := make([]*Diagnostic, 0, len(errs))
+	for _, err := range errs {
+		if isGiteaContextError(err) {
+			continue
+		}
+		d := &Diagnostic{
+			File:    file,
+			Line:    err.Line,
+			Column:  err.Column,
+			Message: err.Message,
+			Kind:    err.Kind,
+		}
+		// the nodes copied from the same anchor have the same problems
+		if n := len(diagnostics); n > 0 && *diagnostics[n-1] == *d {
+			continue
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
+
+// isGiteaContextError returns true for the errors about the `gitea` context, which is an alias of the `github` context
+func isGiteaContextError(err *actionlint.Error) bool {
+	return err.Kind == "expression" && strings.Contains(err.Message, `undefined variable "gitea"`)
+}
+
+// resolveAnchors returns the workflow with its aliases resolved, since actionlint does not support them,
+// and a function which maps the positions in the returned content to the positions in the workflow.
+// The extension keys `x-*` of the workflow which only define anchors are removed.
+// The workflow is returned as it is if it has no aliases or can not be parsed.
+func resolveAnchors(workflow []byte) ([]byte, func(line, column int) (int, int)) {
+	unchanged := func(line, column int) (int, int) {
+		return line, column
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(workflow, &doc); err != nil || !hasAlias(&doc) {
+		return workflow, unchanged
+	}
+	resolved, err := model.ResolveAliases(&doc)
+	if err != nil {
+		return workflow, unchanged
+	}
+	if len(resolved.Content) == 1 && resolved.Content[0].Kind == yaml.MappingNode {
+		mapping := resolved.Content[0]
+		content := make([]*yaml.Node, 0, len(mapping.Content))
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			if strings.HasPrefix(mapping.Content[i].Value, "x-") && mapping.Content[i+1].Anchor != "" {
+				continue
+			}
+			content = append(content, mapping.Content[i], mapping.Content[i+1])
+		}
+		mapping.Content = content
+	}
+	content, err := yaml.Marshal(resolved)
+	if err != nil {
+		return workflow, unchanged
+	}
+	var encoded yaml.Node
+	if err := yaml.Unmarshal(content, &encoded); err != nil {
+		return workflow, unchanged
+	}
+
+	// the positions of the nodes in the content, and the positions of the nodes they are copied from in the workflow
+	type position struct {
+		line, column      int
+		origLine, origCol int
+	}
+	var positions []position
+	var collect func(encoded, resolved *yaml.Node)
+	collect = func(encoded, resolved *yaml.Node) {
+		positions = append(positions, position{encoded.Line, encoded.Column, resolved.Line, resolved.Column})
+		if len(encoded.Content) != len(resolved.Content) {
+			return
+		}
+		for i := range encoded.Content {
+			collect(encoded.Content[i], resolved.Content[i])
+		}
+	}
+	collect(&encoded, resolved)
+	sort.SliceStable(positions, func(i, j int) bool {
+		if positions[i].line != positions[j].line {
+			return positions[i].line < positions[j].line
+		}
+		return positions[i].column < positions[j].column
+	})
+
+	return content, func(line, column int) (int, int) {
+		// the position is in the last node which starts before it
+		i := sort.Search(len(positions), func(i int) bool {
+			return positions[i].line > line || positions[i].line == line && positions[i].column > column
+		}) - 1
+		if i < 0 {
+			return line, column
+		}
+		p := positions[i]
+		if p.line == line {
+			return p.origLine, p.origCol + column - p.column
+		}
+		return p.origLine, p.origCol
+	}
+}
+
+func hasAlias(node *yaml.Node) bool {
+	if node.Kind == yaml.AliasNode {
+		return true
+	}
+	for _, n := range node.Content {
+		if hasAlias(n) {
+			return true
+		}
+	}
+	return false
+}
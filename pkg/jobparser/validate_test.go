@@ -0,0 +1,118 @@
+package jobparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		workflow    string
+		diagnostics []string
+	}{
+		{
+			name: "valid",
+			workflow: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo ${{ gitea.ref }}
+  test:
+    needs: build
+    if: needs.build.result == 'success'
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo test
+`,
+		},
+		{
+			name: "unknown key",
+			workflow: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    step:
+      - run: echo build
+`,
+			diagnostics: []string{
+				`test.yml:3:3: "steps" section is missing in job "build" [syntax-check]`,
+				`test.yml:5:5: unexpected key "step" for "job" section. expected one of "concurrency", "container", "continue-on-error", "defaults", "env", "environment", "if", "name", "needs", "outputs", "permissions", "runs-on", "secrets", "services", "steps", "strategy", "timeout-minutes", "uses", "with" [syntax-check]`,
+			},
+		},
+		{
+			name: "unavailable context",
+			workflow: `on: push
+jobs:
+  build:
+    if: steps.check.outputs.ok == 'true'
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo build
+`,
+			diagnostics: []string{
+				`test.yml:4:9: context "steps" is not allowed here. available contexts are "github", "inputs", "needs", "vars". see https://docs.github.com/en/actions/learn-github-actions/contexts#context-availability for more details [expression]`,
+				`test.yml:4:9: property "check" is not defined in object type {} [expression]`,
+			},
+		},
+		{
+			name: "unknown needs",
+			workflow: `on: push
+jobs:
+  build:
+    needs: prepare
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo build
+`,
+			diagnostics: []string{
+				`test.yml:3:3: job "build" needs job "prepare" which does not exist in this workflow [job-needs]`,
+			},
+		},
+		{
+			name:     "anchors",
+			workflow: string(mustReadTestdata("has_anchors.in.yaml")),
+		},
+		{
+			name: "anchors with problems",
+			workflow: `on: push
+x-job: &job
+  runs-on: ubuntu-latest
+  steps:
+    - run: echo ${{ steps.check.outputs.ok }}
+jobs:
+  build:
+    <<: *job
+    needs: prepare
+  test: *job
+`,
+			// the positions are the positions of the anchors the problems are copied from
+			diagnostics: []string{
+				`test.yml:5:21: property "check" is not defined in object type {} [expression]`,
+				`test.yml:7:3: job "build" needs job "prepare" which does not exist in this workflow [job-needs]`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := []string{}
+			for _, d := range ValidateFile("test.yml", []byte(tt.workflow)) {
+				diagnostics = append(diagnostics, d.String())
+			}
+			if tt.diagnostics == nil {
+				tt.diagnostics = []string{}
+			}
+			assert.Equal(t, tt.diagnostics, diagnostics)
+		})
+	}
+}
+
+func mustReadTestdata(name string) []byte {
+	content, err := testdata.ReadFile("testdata/" + name)
+	if err != nil {
+		panic(err)
+	}
+	return content
+}
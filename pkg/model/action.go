@@ -95,7 +95,7 @@ type Output struct {
 // ReadAction reads an action from a reader
 func ReadAction(in io.Reader) (*Action, error) {
 	a := new(Action)
-	err := yaml.NewDecoder(in).Decode(a)
+	err := decodeResolved(yaml.NewDecoder(in), a)
 	if err != nil {
 		return nil, err
 	}
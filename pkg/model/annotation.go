@@ -0,0 +1,59 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AnnotationLevel is the severity of an annotation
+type AnnotationLevel string
+
+const (
+	AnnotationLevelError   AnnotationLevel = "error"
+	AnnotationLevelWarning AnnotationLevel = "warning"
+	AnnotationLevelNotice  AnnotationLevel = "notice"
+)
+
+// ParseAnnotationLevel returns the level for the name, unknown names are treated as errors like on GitHub
+func ParseAnnotationLevel(name string) AnnotationLevel {
+	switch level := AnnotationLevel(strings.ToLower(strings.TrimSpace(name))); level {
+	case AnnotationLevelWarning, AnnotationLevelNotice:
+		return level
+	}
+	return AnnotationLevelError
+}
+
+// Annotation is a message of a step, optionally referring to a location in a file
+type Annotation struct {
+	Level     AnnotationLevel `json:"level"`
+	Message   string          `json:"message"`
+	Title     string          `json:"title,omitempty"`
+	File      string          `json:"file,omitempty"`
+	Line      int             `json:"line,omitempty"`
+	Column    int             `json:"column,omitempty"`
+	EndLine   int             `json:"endLine,omitempty"`
+	EndColumn int             `json:"endColumn,omitempty"`
+	Code      string          `json:"code,omitempty"`
+}
+
+// String formats the annotation as a workflow command
+func (a *Annotation) String() string {
+	props := make([]string, 0)
+	addProp := func(name, value string) {
+		if value != "" && value != "0" {
+			props = append(props, fmt.Sprintf("%s=%s", name, value))
+		}
+	}
+	addProp("title", a.Title)
+	addProp("file", a.File)
+	addProp("line", fmt.Sprint(a.Line))
+	addProp("col", fmt.Sprint(a.Column))
+	addProp("endLine", fmt.Sprint(a.EndLine))
+	addProp("endColumn", fmt.Sprint(a.EndColumn))
+	addProp("code", a.Code)
+
+	if len(props) == 0 {
+		return fmt.Sprintf("::%s::%s", a.Level, a.Message)
+	}
+	return fmt.Sprintf("::%s %s::%s", a.Level, strings.Join(props, ","), a.Message)
+}
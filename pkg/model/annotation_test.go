@@ -0,0 +1,26 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotationString(t *testing.T) {
+	assert.Equal(t, "::notice::Deployment skipped", (&Annotation{Level: AnnotationLevelNotice, Message: "Deployment skipped"}).String())
+	assert.Equal(t, "::error title=Lint,file=app.js,line=1,col=5::Missing semicolon", (&Annotation{
+		Level:   AnnotationLevelError,
+		Message: "Missing semicolon",
+		Title:   "Lint",
+		File:    "app.js",
+		Line:    1,
+		Column:  5,
+	}).String())
+}
+
+func TestParseAnnotationLevel(t *testing.T) {
+	assert.Equal(t, AnnotationLevelWarning, ParseAnnotationLevel("Warning"))
+	assert.Equal(t, AnnotationLevelNotice, ParseAnnotationLevel("notice"))
+	assert.Equal(t, AnnotationLevelError, ParseAnnotationLevel("error"))
+	assert.Equal(t, AnnotationLevelError, ParseAnnotationLevel("fatal"))
+}
@@ -0,0 +1,159 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nektos/act/pkg/workflowpattern"
+)
+
+// EventFilter contains the details of an event which are matched against
+// the branches, tags and paths filters of a workflow trigger
+type EventFilter struct {
+	Ref          string   // full git ref of the event, e.g. refs/heads/main, the base branch for pull requests
+	ChangedFiles []string // files changed by the event, path filters are not applied if nil
+}
+
+type filterTraceWriter struct{}
+
+func (*filterTraceWriter) Info(format string, args ...interface{}) {
+	log.Debugf(format, args...)
+}
+
+// MatchEventFilter reports whether the workflow is triggered by the event
+// after applying the branches, tags and paths filters of `on.<event>`
+func (w *Workflow) MatchEventFilter(eventName string, filter *EventFilter) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+
+	filters, ok := w.OnEvent(eventName).(map[string]interface{})
+	if !ok {
+		return true, nil
+	}
+	patterns := make(map[string][]string, len(filters))
+	for key := range filters {
+		patterns[key], _ = filterPatterns(filters, key)
+	}
+
+	match, err := MatchEventFilters(eventName, patterns, filter)
+	if err != nil {
+		return false, fmt.Errorf("workflow '%s': %w", w.File, err)
+	}
+	return match, nil
+}
+
+// MatchEventFilters reports whether the event matches the branches, tags and paths filters of `on.<event>`,
+// the filters are the patterns by the name of the filter, e.g. `branches-ignore`.
+// The filters are applied to push and pull request events only.
+//
+//nolint:gocyclo
+func MatchEventFilters(eventName string, filters map[string][]string, filter *EventFilter) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+
+	switch eventName {
+	case "push", "pull_request", "pull_request_target":
+	default:
+		// filters of other events are not supported yet
+		return true, nil
+	}
+
+	branches, hasBranches := filters["branches"]
+	branchesIgnore, hasBranchesIgnore := filters["branches-ignore"]
+	tags, hasTags := filters["tags"]
+	tagsIgnore, hasTagsIgnore := filters["tags-ignore"]
+	paths, hasPaths := filters["paths"]
+	pathsIgnore, hasPathsIgnore := filters["paths-ignore"]
+
+	if hasBranches && hasBranchesIgnore {
+		return false, fmt.Errorf("cannot use both 'branches' and 'branches-ignore' for event '%s'", eventName)
+	}
+	if hasTags && hasTagsIgnore {
+		return false, fmt.Errorf("cannot use both 'tags' and 'tags-ignore' for event '%s'", eventName)
+	}
+	if hasPaths && hasPathsIgnore {
+		return false, fmt.Errorf("cannot use both 'paths' and 'paths-ignore' for event '%s'", eventName)
+	}
+
+	hasBranchFilter := hasBranches || hasBranchesIgnore
+	hasTagFilter := eventName == "push" && (hasTags || hasTagsIgnore)
+
+	isTag := false
+	switch {
+	case strings.HasPrefix(filter.Ref, "refs/heads/"):
+		if !hasBranchFilter && hasTagFilter {
+			// only tags are filtered, so branch pushes do not trigger the workflow
+			return false, nil
+		}
+		match, err := MatchPatterns(branches, branchesIgnore, strings.TrimPrefix(filter.Ref, "refs/heads/"))
+		if err != nil || !match {
+			return false, err
+		}
+	case strings.HasPrefix(filter.Ref, "refs/tags/") && eventName == "push":
+		isTag = true
+		if !hasTagFilter && hasBranchFilter {
+			// only branches are filtered, so tag pushes do not trigger the workflow
+			return false, nil
+		}
+		match, err := MatchPatterns(tags, tagsIgnore, strings.TrimPrefix(filter.Ref, "refs/tags/"))
+		if err != nil || !match {
+			return false, err
+		}
+	}
+
+	// path filters are not evaluated for pushes of tags
+	if filter.ChangedFiles != nil && !isTag {
+		return MatchPatterns(paths, pathsIgnore, filter.ChangedFiles...)
+	}
+
+	return true, nil
+}
+
+// MatchPatterns returns false if the inputs are skipped by the include patterns
+// or completely ignored by the ignore patterns
+func MatchPatterns(include, ignore []string, inputs ...string) (bool, error) {
+	traceWriter := &filterTraceWriter{}
+	if len(include) > 0 {
+		patterns, err := workflowpattern.CompilePatterns(include...)
+		if err != nil {
+			return false, err
+		}
+		if workflowpattern.Skip(patterns, inputs, traceWriter) {
+			return false, nil
+		}
+	}
+	if len(ignore) > 0 {
+		patterns, err := workflowpattern.CompilePatterns(ignore...)
+		if err != nil {
+			return false, err
+		}
+		if workflowpattern.Filter(patterns, inputs, traceWriter) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func filterPatterns(filters map[string]interface{}, key string) ([]string, bool) {
+	val, ok := filters[key]
+	if !ok {
+		return nil, false
+	}
+	switch t := val.(type) {
+	case string:
+		return []string{t}, true
+	case []interface{}:
+		patterns := make([]string, 0, len(t))
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				patterns = append(patterns, s)
+			}
+		}
+		return patterns, true
+	}
+	return nil, true
+}
@@ -0,0 +1,184 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RunsOn is `jobs.<id>.runs-on`, the job runs on a runner of the group which has all of the labels
+type RunsOn struct {
+	Group  string
+	Labels []string
+}
+
+func (r *RunsOn) String() string {
+	s := "[" + strings.Join(r.Labels, ", ") + "]"
+	if r.Group != "" {
+		s += " in group '" + r.Group + "'"
+	}
+	return s
+}
+
+// RunsOnSpec returns `runs-on` of the job without flattening the group into the labels
+func (j *Job) RunsOnSpec() *RunsOn {
+	if j.RawRunsOn.Kind != yaml.MappingNode {
+		return &RunsOn{Labels: nodeAsStringSlice(j.RawRunsOn)}
+	}
+	var val struct {
+		Group  string
+		Labels yaml.Node
+	}
+	if !decodeNode(j.RawRunsOn, &val) {
+		return &RunsOn{}
+	}
+	return &RunsOn{Group: val.Group, Labels: nodeAsStringSlice(val.Labels)}
+}
+
+const (
+	LabelSchemaDocker = "docker"
+	LabelSchemaHost   = "host"
+)
+
+// Label is a label of a runner, `name:docker://image` runs the jobs in containers of the image
+// and `name:host` runs them on the host, a label without schema runs them on the host
+type Label struct {
+	Name   string
+	Schema string
+	Arg    string
+}
+
+// ParseLabel parses a label of a runner
+func ParseLabel(str string) (*Label, error) {
+	parts := strings.SplitN(strings.TrimSpace(str), ":", 3)
+	label := &Label{
+		Name:   parts[0],
+		Schema: LabelSchemaHost,
+	}
+	if label.Name == "" {
+		return nil, fmt.Errorf("invalid label '%s': empty name", str)
+	}
+	if len(parts) > 1 {
+		label.Schema = parts[1]
+	}
+	if len(parts) > 2 {
+		label.Arg = parts[2]
+	}
+
+	switch label.Schema {
+	case LabelSchemaHost:
+		if label.Arg != "" {
+			return nil, fmt.Errorf("invalid label '%s': schema '%s' has no arguments", str, label.Schema)
+		}
+	case LabelSchemaDocker:
+		if strings.TrimPrefix(label.Arg, "//") == "" {
+			return nil, fmt.Errorf("invalid label '%s': schema '%s' requires an image", str, label.Schema)
+		}
+	default:
+		return nil, fmt.Errorf("invalid label '%s': unsupported schema '%s'", str, label.Schema)
+	}
+	return label, nil
+}
+
+// Platform returns the image of the label, or `-self-hosted` if the jobs run on the host
+func (l *Label) Platform() string {
+	if l.Schema == LabelSchemaDocker {
+		return strings.TrimPrefix(l.Arg, "//")
+	}
+	return "-self-hosted"
+}
+
+func (l *Label) String() string {
+	if l.Arg != "" {
+		return l.Name + ":" + l.Schema + ":" + l.Arg
+	}
+	return l.Name + ":" + l.Schema
+}
+
+// RunnerLabels are the group and the labels of a runner
+type RunnerLabels struct {
+	Group  string
+	Labels []*Label
+}
+
+// NewRunnerLabels parses the labels of a runner of the group
+func NewRunnerLabels(group string, labels ...string) (*RunnerLabels, error) {
+	r := &RunnerLabels{Group: group}
+	for _, str := range labels {
+		label, err := ParseLabel(str)
+		if err != nil {
+			return nil, err
+		}
+		r.Labels = append(r.Labels, label)
+	}
+	return r, nil
+}
+
+// Match reports whether the runner is in the group of `runs-on` and has all of its labels,
+// labels are compared case-insensitively
+func (r *RunnerLabels) Match(runsOn *RunsOn) bool {
+	if runsOn.Group != "" && !strings.EqualFold(runsOn.Group, r.Group) {
+		return false
+	}
+	if len(runsOn.Labels) == 0 {
+		// a group alone is enough for a runner of the group
+		return runsOn.Group != ""
+	}
+	for _, name := range runsOn.Labels {
+		if r.label(name) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Pick returns the label the runner runs the job with, which is the first label of `runs-on` the runner has,
+// or nil if the runner does not match
+func (r *RunnerLabels) Pick(runsOn *RunsOn) *Label {
+	if !r.Match(runsOn) {
+		return nil
+	}
+	for _, name := range runsOn.Labels {
+		if label := r.label(name); label != nil {
+			return label
+		}
+	}
+	if len(r.Labels) > 0 {
+		return r.Labels[0]
+	}
+	return nil
+}
+
+func (r *RunnerLabels) label(name string) *Label {
+	for _, label := range r.Labels {
+		if strings.EqualFold(label.Name, name) {
+			return label
+		}
+	}
+	return nil
+}
+
+// NoRunnerError is returned if no runner can satisfy `runs-on` of a job
+type NoRunnerError struct {
+	RunsOn *RunsOn
+}
+
+func (e *NoRunnerError) Error() string {
+	return fmt.Sprintf("no runner can satisfy labels %s", e.RunsOn)
+}
+
+// SelectRunner returns the runner which matches `runs-on` best, which is the matching runner with the fewest labels,
+// the first one is preferred if several runners match equally
+func SelectRunner(runners []*RunnerLabels, runsOn *RunsOn) (*RunnerLabels, error) {
+	var best *RunnerLabels
+	for _, r := range runners {
+		if r.Match(runsOn) && (best == nil || len(r.Labels) < len(best.Labels)) {
+			best = r
+		}
+	}
+	if best == nil {
+		return nil, &NoRunnerError{RunsOn: runsOn}
+	}
+	return best, nil
+}
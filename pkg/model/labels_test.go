@@ -0,0 +1,101 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabel(t *testing.T) {
+	tables := []struct {
+		str      string
+		label    *Label
+		platform string
+		wantErr  bool
+	}{
+		{"ubuntu-latest", &Label{Name: "ubuntu-latest", Schema: "host"}, "-self-hosted", false},
+		{"linux:host", &Label{Name: "linux", Schema: "host"}, "-self-hosted", false},
+		{"ubuntu-22.04:docker://node:16-bullseye", &Label{Name: "ubuntu-22.04", Schema: "docker", Arg: "//node:16-bullseye"}, "node:16-bullseye", false},
+		{"ubuntu-latest:docker://ghcr.io/catthehacker/ubuntu:act-latest", &Label{Name: "ubuntu-latest", Schema: "docker", Arg: "//ghcr.io/catthehacker/ubuntu:act-latest"}, "ghcr.io/catthehacker/ubuntu:act-latest", false},
+		{"ubuntu:docker", nil, "", true},
+		{"ubuntu:host:arg", nil, "", true},
+		{"ubuntu:vm:image", nil, "", true},
+		{":host", nil, "", true},
+	}
+	for _, table := range tables {
+		t.Run(table.str, func(t *testing.T) {
+			label, err := ParseLabel(table.str)
+			if table.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, table.label, label)
+			assert.Equal(t, table.platform, label.Platform())
+		})
+	}
+}
+
+func TestRunsOnSpec(t *testing.T) {
+	workflow, err := ReadWorkflow(strings.NewReader(`
+jobs:
+  labels:
+    runs-on: [self-hosted, linux]
+  group:
+    runs-on:
+      group: large
+      labels: [linux, x64]
+  label:
+    runs-on: ubuntu-latest
+`))
+	require.NoError(t, err)
+	assert.Equal(t, &RunsOn{Labels: []string{"self-hosted", "linux"}}, workflow.Jobs["labels"].RunsOnSpec())
+	assert.Equal(t, &RunsOn{Group: "large", Labels: []string{"linux", "x64"}}, workflow.Jobs["group"].RunsOnSpec())
+	assert.Equal(t, &RunsOn{Labels: []string{"ubuntu-latest"}}, workflow.Jobs["label"].RunsOnSpec())
+}
+
+func TestSelectRunner(t *testing.T) {
+	newRunner := func(group string, labels ...string) *RunnerLabels {
+		r, err := NewRunnerLabels(group, labels...)
+		require.NoError(t, err)
+		return r
+	}
+	generic := newRunner("", "self-hosted", "linux", "x64", "gpu", "ubuntu-latest:docker://node:16")
+	linux := newRunner("", "self-hosted", "linux", "x64")
+	large := newRunner("large", "linux", "x64:docker://node:20")
+
+	runners := []*RunnerLabels{generic, linux, large}
+	tables := []struct {
+		name   string
+		runsOn *RunsOn
+		runner *RunnerLabels
+		label  string
+	}{
+		{"single label", &RunsOn{Labels: []string{"ubuntu-latest"}}, generic, "ubuntu-latest"},
+		{"fewest labels", &RunsOn{Labels: []string{"Self-Hosted", "linux"}}, linux, "self-hosted"},
+		{"all labels", &RunsOn{Labels: []string{"linux", "gpu"}}, generic, "linux"},
+		{"group", &RunsOn{Group: "large", Labels: []string{"x64"}}, large, "x64"},
+		{"group only", &RunsOn{Group: "large"}, large, "linux"},
+		{"unknown label", &RunsOn{Labels: []string{"windows"}}, nil, ""},
+		{"unknown group", &RunsOn{Group: "small", Labels: []string{"linux"}}, nil, ""},
+		{"no labels", &RunsOn{}, nil, ""},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			runner, err := SelectRunner(runners, table.runsOn)
+			if table.runner == nil {
+				var noRunner *NoRunnerError
+				assert.ErrorAs(t, err, &noRunner)
+				return
+			}
+			require.NoError(t, err)
+			assert.Same(t, table.runner, runner)
+			assert.Equal(t, table.label, runner.Pick(table.runsOn).Name)
+		})
+	}
+
+	_, err := SelectRunner(runners, &RunsOn{Group: "small", Labels: []string{"linux", "arm64"}})
+	assert.EqualError(t, err, "no runner can satisfy labels [linux, arm64] in group 'small'")
+}
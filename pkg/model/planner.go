@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -16,9 +17,11 @@ import (
 // WorkflowPlanner contains methods for creating plans
 type WorkflowPlanner interface {
 	PlanEvent(eventName string) (*Plan, error)
+	PlanEventWithFilter(eventName string, filter *EventFilter) (*Plan, error)
 	PlanJob(jobName string) (*Plan, error)
 	PlanAll() (*Plan, error)
 	GetEvents() []string
+	GetDueSchedules(from, to time.Time) ([]*ScheduledWorkflow, error)
 }
 
 // Plan contains a list of stages to run in series
@@ -211,6 +214,12 @@ type workflowPlanner struct {
 
 // PlanEvent builds a new list of runs to execute in parallel for an event name
 func (wp *workflowPlanner) PlanEvent(eventName string) (*Plan, error) {
+	return wp.PlanEventWithFilter(eventName, nil)
+}
+
+// PlanEventWithFilter builds a new list of runs to execute in parallel for an event name,
+// leaving out the workflows whose branches, tags or paths filters do not match the event
+func (wp *workflowPlanner) PlanEventWithFilter(eventName string, filter *EventFilter) (*Plan, error) {
 	plan := new(Plan)
 	if len(wp.workflows) == 0 {
 		log.Debug("no workflows found by planner")
@@ -227,6 +236,16 @@ func (wp *workflowPlanner) PlanEvent(eventName string) (*Plan, error) {
 
 		for _, e := range events {
 			if e == eventName {
+				match, err := w.MatchEventFilter(eventName, filter)
+				if err != nil {
+					log.Warn(err)
+					lastErr = err
+					continue
+				}
+				if !match {
+					log.Debugf("filters of event '%s' do not match for workflow: %s", eventName, w.File)
+					continue
+				}
 				stages, err := createStages(w, w.GetJobIDs()...)
 				if err != nil {
 					log.Warn(err)
@@ -282,6 +301,12 @@ func (wp *workflowPlanner) PlanAll() (*Plan, error) {
 	return plan, lastErr
 }
 
+// GetDueSchedules gets the workflows which are due to be triggered by `on.schedule` after from
+// and up to and including to
+func (wp *workflowPlanner) GetDueSchedules(from, to time.Time) ([]*ScheduledWorkflow, error) {
+	return dueSchedules(wp.workflows, from, to)
+}
+
 // GetEvents gets all the events in the workflows file
 func (wp *workflowPlanner) GetEvents() []string {
 	events := make([]string, 0)
@@ -61,3 +61,42 @@ func TestWorkflow(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, result)
 }
+
+func TestPlanEventWithFilter(t *testing.T) {
+	log.SetLevel(log.DebugLevel)
+
+	planner, err := NewWorkflowPlanner("testdata/event-filters", true)
+	assert.NoError(t, err)
+
+	tables := []struct {
+		name      string
+		eventName string
+		filter    *EventFilter
+		workflows []string
+	}{
+		{"no filter", "push", nil, []string{"branches", "paths", "tags"}},
+		{"main branch", "push", &EventFilter{Ref: "refs/heads/main"}, []string{"branches", "paths"}},
+		{"release branch", "push", &EventFilter{Ref: "refs/heads/releases/v1/rc"}, []string{"branches", "paths"}},
+		{"ignored branch", "push", &EventFilter{Ref: "refs/heads/dependabot/npm"}, []string{}},
+		{"tag", "push", &EventFilter{Ref: "refs/tags/v1.0.0"}, []string{"tags"}},
+		{"unmatched tag", "push", &EventFilter{Ref: "refs/tags/nightly"}, []string{}},
+		{"ignored paths", "push", &EventFilter{Ref: "refs/heads/main", ChangedFiles: []string{"docs/index.md", "README.md"}}, []string{"branches"}},
+		{"changed paths", "push", &EventFilter{Ref: "refs/heads/main", ChangedFiles: []string{"docs/index.md", "main.go"}}, []string{"branches", "paths"}},
+		{"pull request base", "pull_request", &EventFilter{Ref: "refs/heads/main"}, []string{"branches"}},
+		{"pull request other base", "pull_request", &EventFilter{Ref: "refs/heads/develop"}, []string{}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			plan, err := planner.PlanEventWithFilter(table.eventName, table.filter)
+			assert.NoError(t, err)
+			workflows := []string{}
+			for _, stage := range plan.Stages {
+				for _, run := range stage.Runs {
+					workflows = append(workflows, run.Workflow.Name)
+				}
+			}
+			assert.ElementsMatch(t, table.workflows, workflows)
+		})
+	}
+}
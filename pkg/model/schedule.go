@@ -0,0 +1,87 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser parses the POSIX cron syntax supported by `on.schedule`,
+// which has five fields and no descriptors like `@daily`
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// Schedule is a `cron` of `on.schedule`, the times are evaluated in UTC
+type Schedule struct {
+	Cron     string
+	schedule cron.Schedule
+}
+
+// ParseSchedule validates the cron syntax of a schedule
+func ParseSchedule(spec string) (*Schedule, error) {
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron '%s': %w", spec, err)
+	}
+	return &Schedule{Cron: spec, schedule: schedule}, nil
+}
+
+// Next returns the first fire time after t
+func (s *Schedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t.UTC())
+}
+
+// Between returns the fire times after from and up to and including to.
+// A cron which never fires, e.g. on February 30th, has no fire times.
+func (s *Schedule) Between(from, to time.Time) []time.Time {
+	var times []time.Time
+	for prev, next := from, s.Next(from); !next.IsZero() && next.After(prev) && !next.After(to); prev, next = next, s.Next(next) {
+		times = append(times, next)
+	}
+	return times
+}
+
+// Schedules returns the parsed schedules of the workflow
+func (w *Workflow) Schedules() ([]*Schedule, error) {
+	crons := w.OnSchedule()
+	schedules := make([]*Schedule, 0, len(crons))
+	for _, spec := range crons {
+		schedule, err := ParseSchedule(spec)
+		if err != nil {
+			return nil, fmt.Errorf("workflow '%s': %w", w.File, err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+// ScheduledWorkflow is a workflow which is due at a fire time of one of its schedules
+type ScheduledWorkflow struct {
+	Workflow *Workflow
+	Cron     string
+	Time     time.Time
+}
+
+// dueSchedules returns the fire times of the schedules of the workflows between from and to,
+// sorted by time
+func dueSchedules(workflows []*Workflow, from, to time.Time) ([]*ScheduledWorkflow, error) {
+	var due []*ScheduledWorkflow
+	var lastErr error
+	for _, w := range workflows {
+		schedules, err := w.Schedules()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, schedule := range schedules {
+			for _, t := range schedule.Between(from, to) {
+				due = append(due, &ScheduledWorkflow{Workflow: w, Cron: schedule.Cron, Time: t})
+			}
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].Time.Before(due[j].Time)
+	})
+	return due, lastErr
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tables := []struct {
+		cron  string
+		valid bool
+	}{
+		{"* * * * *", true},
+		{"*/5 1-3 * JAN,FEB MON-FRI", true},
+		{"0 0 1 1 *", true},
+		{"0 0 * * 0", true},
+		{"@daily", false},
+		{"0 0 * *", false},
+		{"0 0 0 * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+	}
+	for _, table := range tables {
+		t.Run(table.cron, func(t *testing.T) {
+			_, err := ParseSchedule(table.cron)
+			if table.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	schedule, err := ParseSchedule("30 2 * * *")
+	require.NoError(t, err)
+
+	// the schedule is evaluated in UTC
+	from := time.Date(2024, 3, 1, 23, 0, 0, 0, time.FixedZone("UTC-5", -5*60*60))
+	assert.Equal(t, time.Date(2024, 3, 3, 2, 30, 0, 0, time.UTC), schedule.Next(from))
+
+	times := schedule.Between(time.Date(2024, 3, 1, 2, 30, 0, 0, time.UTC), time.Date(2024, 3, 3, 2, 30, 0, 0, time.UTC))
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 3, 2, 2, 30, 0, 0, time.UTC),
+		time.Date(2024, 3, 3, 2, 30, 0, 0, time.UTC),
+	}, times)
+}
+
+func TestScheduleBetweenNeverFires(t *testing.T) {
+	// the cron is valid, but February has no 30th day, so Next returns the zero time
+	schedule, err := ParseSchedule("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, schedule.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero())
+
+	done := make(chan []time.Time)
+	go func() {
+		done <- schedule.Between(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	}()
+	select {
+	case times := <-done:
+		assert.Empty(t, times)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Between must return for a cron which never fires")
+	}
+}
+
+func TestGetDueSchedules(t *testing.T) {
+	planner, err := NewWorkflowPlanner("testdata/schedules", true)
+	require.NoError(t, err)
+
+	// Friday
+	from := time.Date(2024, 3, 1, 11, 55, 0, 0, time.UTC)
+	due, err := planner.GetDueSchedules(from, from.Add(20*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, "quarter-hourly", due[0].Workflow.Name)
+	assert.Equal(t, "*/15 * * * 1-5", due[0].Cron)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), due[0].Time)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 15, 0, 0, time.UTC), due[1].Time)
+
+	// Sunday
+	from = time.Date(2024, 3, 3, 2, 0, 0, 0, time.UTC)
+	due, err = planner.GetDueSchedules(from, from.Add(10*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, "nightly", due[0].Workflow.Name)
+	assert.Equal(t, time.Date(2024, 3, 3, 2, 30, 0, 0, time.UTC), due[0].Time)
+	assert.Equal(t, "0 12 * * 0", due[1].Cron)
+
+	due, err = planner.GetDueSchedules(from, from.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
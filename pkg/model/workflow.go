@@ -15,12 +15,13 @@ import (
 
 // Workflow is the structure of the files in .github/workflows
 type Workflow struct {
-	File     string
-	Name     string            `yaml:"name"`
-	RawOn    yaml.Node         `yaml:"on"`
-	Env      map[string]string `yaml:"env"`
-	Jobs     map[string]*Job   `yaml:"jobs"`
-	Defaults Defaults          `yaml:"defaults"`
+	File           string
+	Name           string            `yaml:"name"`
+	RawOn          yaml.Node         `yaml:"on"`
+	Env            map[string]string `yaml:"env"`
+	Jobs           map[string]*Job   `yaml:"jobs"`
+	Defaults       Defaults          `yaml:"defaults"`
+	RawConcurrency yaml.Node         `yaml:"concurrency"`
 }
 
 // On events for the workflow
@@ -55,6 +56,28 @@ func (w *Workflow) On() []string {
 	return nil
 }
 
+// Concurrency details for the workflow
+func (w *Workflow) Concurrency() *Concurrency {
+	return decodeConcurrency(w.RawConcurrency)
+}
+
+func decodeConcurrency(node yaml.Node) *Concurrency {
+	var val *Concurrency
+	switch node.Kind {
+	case yaml.ScalarNode:
+		val = new(Concurrency)
+		if !decodeNode(node, &val.Group) {
+			return nil
+		}
+	case yaml.MappingNode:
+		val = new(Concurrency)
+		if !decodeNode(node, val) {
+			return nil
+		}
+	}
+	return val
+}
+
 func (w *Workflow) OnEvent(event string) interface{} {
 	if w.RawOn.Kind == yaml.MappingNode {
 		var val map[string]interface{}
@@ -182,22 +205,24 @@ func (w *Workflow) WorkflowCallConfig() *WorkflowCall {
 
 // Job is the structure of one job in a workflow
 type Job struct {
-	Name           string                    `yaml:"name"`
-	RawNeeds       yaml.Node                 `yaml:"needs"`
-	RawRunsOn      yaml.Node                 `yaml:"runs-on"`
-	Env            yaml.Node                 `yaml:"env"`
-	If             yaml.Node                 `yaml:"if"`
-	Steps          []*Step                   `yaml:"steps"`
-	TimeoutMinutes string                    `yaml:"timeout-minutes"`
-	Services       map[string]*ContainerSpec `yaml:"services"`
-	Strategy       *Strategy                 `yaml:"strategy"`
-	RawContainer   yaml.Node                 `yaml:"container"`
-	Defaults       Defaults                  `yaml:"defaults"`
-	Outputs        map[string]string         `yaml:"outputs"`
-	Uses           string                    `yaml:"uses"`
-	With           map[string]interface{}    `yaml:"with"`
-	RawSecrets     yaml.Node                 `yaml:"secrets"`
-	Result         string
+	Name               string                    `yaml:"name"`
+	RawNeeds           yaml.Node                 `yaml:"needs"`
+	RawRunsOn          yaml.Node                 `yaml:"runs-on"`
+	Env                yaml.Node                 `yaml:"env"`
+	If                 yaml.Node                 `yaml:"if"`
+	Steps              []*Step                   `yaml:"steps"`
+	TimeoutMinutes     string                    `yaml:"timeout-minutes"`
+	Services           map[string]*ContainerSpec `yaml:"services"`
+	Strategy           *Strategy                 `yaml:"strategy"`
+	RawContainer       yaml.Node                 `yaml:"container"`
+	Defaults           Defaults                  `yaml:"defaults"`
+	Outputs            map[string]string         `yaml:"outputs"`
+	Uses               string                    `yaml:"uses"`
+	With               map[string]interface{}    `yaml:"with"`
+	RawSecrets         yaml.Node                 `yaml:"secrets"`
+	RawConcurrency     yaml.Node                 `yaml:"concurrency"`
+	RawContinueOnError string                    `yaml:"continue-on-error"`
+	Result             string
 }
 
 // Strategy for the job
@@ -209,6 +234,12 @@ type Strategy struct {
 	RawMatrix         yaml.Node `yaml:"matrix"`
 }
 
+// Concurrency of the workflow or job, runs in the same group are executed one at a time
+type Concurrency struct {
+	Group            string `yaml:"group"`
+	CancelInProgress string `yaml:"cancel-in-progress"`
+}
+
 // Default settings that will apply to all steps in the job or workflow
 type Defaults struct {
 	Run RunDefaults `yaml:"run"`
@@ -277,6 +308,11 @@ func (j *Job) Secrets() map[string]string {
 	return val
 }
 
+// Concurrency details for the job
+func (j *Job) Concurrency() *Concurrency {
+	return decodeConcurrency(j.RawConcurrency)
+}
+
 // Container details for the job
 func (j *Job) Container() *ContainerSpec {
 	var val *ContainerSpec
@@ -719,7 +755,7 @@ func (s *Step) Type() StepType {
 // ReadWorkflow returns a list of jobs for a given workflow file reader
 func ReadWorkflow(in io.Reader) (*Workflow, error) {
 	w := new(Workflow)
-	err := yaml.NewDecoder(in).Decode(w)
+	err := decodeResolved(yaml.NewDecoder(in), w)
 	return w, err
 }
 
@@ -153,6 +153,53 @@ jobs:
 	assert.Contains(t, workflow.On(), "pull_request")
 }
 
+func TestReadWorkflow_Anchors(t *testing.T) {
+	yaml := `
+name: anchors
+on:
+  push: &filters
+    branches: [main]
+  pull_request: *filters
+env: &env
+  GOFLAGS: -mod=mod
+jobs:
+  build:
+    runs-on: &runs-on [self-hosted, linux]
+    strategy:
+      matrix: &matrix
+        version: ["1.20", "1.21"]
+    env: *env
+    steps: &steps
+      - id: setup
+        uses: actions/setup-go@v3
+      - run: go test ./...
+  test:
+    needs: &needs [build]
+    runs-on: *runs-on
+    strategy:
+      matrix: *matrix
+    env:
+      <<: *env
+      CGO_ENABLED: "0"
+    steps: *steps
+`
+
+	workflow, err := ReadWorkflow(strings.NewReader(yaml))
+	assert.NoError(t, err, "read workflow should succeed")
+	assert.ElementsMatch(t, []string{"push", "pull_request"}, workflow.On())
+	assert.Equal(t, map[string]interface{}{"branches": []interface{}{"main"}}, workflow.OnEvent("pull_request"))
+
+	job := workflow.Jobs["test"]
+	assert.Equal(t, []string{"build"}, job.Needs())
+	assert.Equal(t, []string{"self-hosted", "linux"}, job.RunsOn())
+	assert.Equal(t, map[string]string{"GOFLAGS": "-mod=mod", "CGO_ENABLED": "0"}, job.Environment())
+	assert.Len(t, job.Steps, 2)
+	assert.Equal(t, "setup", job.Steps[0].ID)
+	matrixes, err := job.GetMatrixes()
+	assert.NoError(t, err)
+	assert.Len(t, matrixes, 2)
+}
+
 func TestReadWorkflow_RunsOnLabels(t *testing.T) {
 	yaml := `
 name: local-action-docker-url
@@ -483,6 +530,34 @@ func TestReadWorkflow_Strategy(t *testing.T) {
 	assert.Equal(t, job.Strategy.FailFast, false)
 }
 
+func TestReadWorkflow_Concurrency(t *testing.T) {
+	yaml := `
+name: local-action-docker-url
+on: push
+concurrency: ${{ github.workflow }}-${{ github.ref }}
+
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    concurrency:
+      group: deploy-${{ github.ref }}
+      cancel-in-progress: true
+    steps:
+    - run: echo deploy
+  test:
+    runs-on: ubuntu-latest
+    steps:
+    - run: echo test
+`
+
+	workflow, err := ReadWorkflow(strings.NewReader(yaml))
+	assert.NoError(t, err, "read workflow should succeed")
+
+	assert.Equal(t, &Concurrency{Group: "${{ github.workflow }}-${{ github.ref }}"}, workflow.Concurrency())
+	assert.Equal(t, &Concurrency{Group: "deploy-${{ github.ref }}", CancelInProgress: "true"}, workflow.Jobs["deploy"].Concurrency())
+	assert.Nil(t, workflow.Jobs["test"].Concurrency())
+}
+
 func TestStep_ShellCommand(t *testing.T) {
 	tests := []struct {
 		shell string
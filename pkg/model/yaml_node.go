@@ -0,0 +1,205 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// errExcessiveAliasing is returned when resolving the aliases of a document would expand it too much,
+// like yaml.v3 the expansion is limited relative to the size of the resolved document
+var errExcessiveAliasing = errors.New("yaml: document contains excessive aliasing")
+
+// ResolveAliases returns a copy of the node whose aliases are replaced with the content of their anchors,
+// and whose merge keys `<<` are merged into their mappings.
+// The nodes which look at Kind do not have to deal with aliases then.
+func ResolveAliases(node *yaml.Node) (*yaml.Node, error) {
+	r := &aliasResolver{resolving: map[*yaml.Node]bool{}}
+	return r.resolve(node)
+}
+
+type aliasResolver struct {
+	resolving map[*yaml.Node]bool
+	// the number of nodes in the resolved document and the number of them copied from anchors
+	count      int
+	aliasCount int
+	aliasDepth int
+}
+
+// allowedAliasRatio returns the share of the nodes which may be copied from anchors, the same limit yaml.v3 applies
+func allowedAliasRatio(count int) float64 {
+	switch {
+	case count <= 400000:
+		return 0.99
+	case count >= 4000000:
+		return 0.10
+	default:
+		return 0.99 - 0.89*(float64(count-400000)/3600000)
+	}
+}
+
+func (r *aliasResolver) resolve(node *yaml.Node) (*yaml.Node, error) {
+	if node == nil {
+		return nil, nil
+	}
+	r.count++
+	if r.aliasDepth > 0 {
+		r.aliasCount++
+	}
+	if r.count > 4000 && float64(r.aliasCount) > float64(r.count)*allowedAliasRatio(r.count) {
+		return nil, errExcessiveAliasing
+	}
+
+	if node.Kind == yaml.AliasNode {
+		if node.Alias == nil || r.resolving[node.Alias] {
+			// the anchor contains the alias itself
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: node.Line, Column: node.Column}, nil
+		}
+		r.resolving[node.Alias] = true
+		r.aliasDepth++
+		resolved, err := r.resolve(node.Alias)
+		r.aliasDepth--
+		delete(r.resolving, node.Alias)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Anchor = ""
+		resolved.Line = node.Line
+		resolved.Column = node.Column
+		return resolved, nil
+	}
+
+	resolved := *node
+	resolved.Content = make([]*yaml.Node, 0, len(node.Content))
+	if node.Kind != yaml.MappingNode {
+		for _, n := range node.Content {
+			value, err := r.resolve(n)
+			if err != nil {
+				return nil, err
+			}
+			resolved.Content = append(resolved.Content, value)
+		}
+		return &resolved, nil
+	}
+
+	// the keys of the mapping take precedence over the merged keys
+	defined := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !isMergeKey(node.Content[i]) {
+			defined[node.Content[i].Value] = true
+		}
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		value, err := r.resolve(node.Content[i+1])
+		if err != nil {
+			return nil, err
+		}
+		if !isMergeKey(node.Content[i]) {
+			key, err := r.resolve(node.Content[i])
+			if err != nil {
+				return nil, err
+			}
+			resolved.Content = append(resolved.Content, key, value)
+			continue
+		}
+		merged := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			merged = value.Content
+		}
+		for _, m := range merged {
+			if m.Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 0; j+1 < len(m.Content); j += 2 {
+				if defined[m.Content[j].Value] {
+					continue
+				}
+				defined[m.Content[j].Value] = true
+				resolved.Content = append(resolved.Content, m.Content[j], m.Content[j+1])
+			}
+		}
+	}
+	return &resolved, nil
+}
+
+func isMergeKey(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Value == "<<" && node.ShortTag() == "!!merge"
+}
+
+// decodeResolved decodes the document with its aliases resolved.
+// Only the keys of the document which are fields of out are resolved, the others are never decoded.
+func decodeResolved(decoder *yaml.Decoder, out interface{}) error {
+	node := yaml.Node{}
+	if err := decoder.Decode(&node); err != nil {
+		return err
+	}
+	doc := &node
+	if len(doc.Content) == 1 && doc.Content[0].Kind == yaml.MappingNode {
+		if fields := yamlFields(reflect.TypeOf(out)); fields != nil {
+			doc = withKeys(doc, fields)
+		}
+	}
+	resolved, err := ResolveAliases(doc)
+	if err != nil {
+		return err
+	}
+	return resolved.Decode(out)
+}
+
+// withKeys returns a copy of the document whose mapping only keeps the keys in the fields and merge keys
+func withKeys(doc *yaml.Node, fields map[string]bool) *yaml.Node {
+	mapping := *doc.Content[0]
+	mapping.Content = make([]*yaml.Node, 0, len(doc.Content[0].Content))
+	for i := 0; i+1 < len(doc.Content[0].Content); i += 2 {
+		key := doc.Content[0].Content[i]
+		if fields[key.Value] || isMergeKey(key) {
+			mapping.Content = append(mapping.Content, key, doc.Content[0].Content[i+1])
+		}
+	}
+	filtered := *doc
+	filtered.Content = []*yaml.Node{&mapping}
+	return &filtered
+}
+
+// yamlFields returns the keys yaml.v3 decodes into the struct t points to,
+// or nil if t is no pointer to a struct or the struct decodes every key
+func yamlFields(t reflect.Type) map[string]bool {
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	fields := map[string]bool{}
+	inlineMap := false
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			tag := field.Tag.Get("yaml")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if strings.Contains(","+opts+",", ",inline,") {
+				if field.Type.Kind() == reflect.Struct {
+					collect(field.Type)
+				} else {
+					inlineMap = true
+				}
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			fields[name] = true
+		}
+	}
+	collect(t.Elem())
+	if inlineMap {
+		return nil
+	}
+	return fields
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestResolveAliases(t *testing.T) {
+	node := yaml.Node{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+base: &base
+  a: 1
+  b: 2
+other: &other
+  c: 3
+merged:
+  <<: [*base, *other]
+  b: 4
+list: &list [x, y]
+alias: *list
+`), &node))
+
+	resolved, err := ResolveAliases(&node)
+	require.NoError(t, err)
+	var val map[string]interface{}
+	require.NoError(t, resolved.Decode(&val))
+	assert.Equal(t, map[string]interface{}{"a": 1, "b": 4, "c": 3}, val["merged"])
+	assert.Equal(t, []interface{}{"x", "y"}, val["alias"])
+
+	// the original node is not changed
+	alias := node.Content[0].Content[9]
+	assert.Equal(t, yaml.AliasNode, alias.Kind)
+	resolvedAlias := resolved.Content[0].Content[9]
+	assert.Equal(t, yaml.SequenceNode, resolvedAlias.Kind)
+	assert.Empty(t, resolvedAlias.Anchor)
+	assert.Equal(t, alias.Line, resolvedAlias.Line)
+}
+
+func TestResolveAliasesExcessiveAliasing(t *testing.T) {
+	bomb := `
+a: &a [x, x, x, x, x, x, x, x, x]
+b: &b [*a, *a, *a, *a, *a, *a, *a, *a, *a]
+c: &c [*b, *b, *b, *b, *b, *b, *b, *b, *b]
+d: &d [*c, *c, *c, *c, *c, *c, *c, *c, *c]
+e: &e [*d, *d, *d, *d, *d, *d, *d, *d, *d]
+f: &f [*e, *e, *e, *e, *e, *e, *e, *e, *e]
+g: &g [*f, *f, *f, *f, *f, *f, *f, *f, *f]
+`
+	node := yaml.Node{}
+	require.NoError(t, yaml.Unmarshal([]byte(bomb), &node))
+
+	start := time.Now()
+	_, err := ResolveAliases(&node)
+	assert.ErrorIs(t, err, errExcessiveAliasing)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	nested := strings.ReplaceAll(bomb, "\n", "\n  ")
+	_, err = ReadWorkflow(strings.NewReader("on: push\nenv:" + nested + "\njobs: {}\n"))
+	assert.ErrorIs(t, err, errExcessiveAliasing)
+
+	// keys which are never decoded are not resolved
+	workflow, err := ReadWorkflow(strings.NewReader("on: push\nx-bomb:" + nested + "\njobs: {}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"push"}, workflow.On())
+}
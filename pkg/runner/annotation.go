@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/nektos/act/pkg/common"
+	"github.com/nektos/act/pkg/model"
+)
+
+// AnnotationHandler receives the annotations reported by the steps of the jobs,
+// e.g. by the `error`, `warning` and `notice` commands or by problem matchers
+type AnnotationHandler func(source AnnotationSource, annotation *model.Annotation)
+
+// AnnotationSource identifies the job and the step which reported an annotation
+type AnnotationSource struct {
+	JobID   string                 // the id of the job in the workflow
+	JobName string                 // the unique name of the job, including the matrix permutation
+	Matrix  map[string]interface{} // the matrix permutation of the job
+	StepID  string                 // the id of the step of the job
+}
+
+// annotationFromCommand creates the annotation of an `error`, `warning` or `notice` command
+func annotationFromCommand(command string, kvPairs map[string]string, arg string) *model.Annotation {
+	annotation := &model.Annotation{
+		Level:   model.ParseAnnotationLevel(command),
+		Message: arg,
+		Title:   kvPairs["title"],
+		File:    kvPairs["file"],
+	}
+	intProperty := func(value *int, names ...string) {
+		for _, name := range names {
+			if v, err := strconv.Atoi(kvPairs[name]); err == nil {
+				*value = v
+				return
+			}
+		}
+	}
+	intProperty(&annotation.Line, "line")
+	intProperty(&annotation.Column, "col", "column")
+	intProperty(&annotation.EndLine, "endLine")
+	intProperty(&annotation.EndColumn, "endColumn")
+	return annotation
+}
+
+// addAnnotation records the masked annotation in the job and passes it to the annotation handler
+func (rc *RunContext) addAnnotation(ctx context.Context, annotation *model.Annotation) {
+	annotation.Message = rc.mask(annotation.Message)
+	annotation.Title = rc.mask(annotation.Title)
+	common.Logger(ctx).WithField("annotation", annotation).Infof("%s", annotation)
+
+	jobRc := rc.jobRunContext()
+	jobRc.Annotations = append(jobRc.Annotations, annotation)
+
+	if rc.Config != nil && rc.Config.AnnotationHandler != nil {
+		source := AnnotationSource{
+			Matrix: jobRc.Matrix,
+			StepID: jobRc.CurrentStep,
+		}
+		if jobRc.Run != nil {
+			source.JobID = jobRc.Run.JobID
+			source.JobName = jobRc.String()
+		}
+		rc.Config.AnnotationHandler(source, annotation)
+	}
+}
@@ -38,6 +38,7 @@ func (rc *RunContext) commandHandler(ctx context.Context) common.LineHandler {
 	return func(line string) bool {
 		command, kvPairs, arg, ok := tryParseRawActionCommand(line)
 		if !ok {
+			rc.matchProblems(ctx, line)
 			return true
 		}
 
@@ -58,10 +59,8 @@ func (rc *RunContext) commandHandler(ctx context.Context) common.LineHandler {
 			rc.addPath(ctx, arg)
 		case "debug":
 			logger.Infof("%s", line)
-		case "warning":
-			logger.Infof("%s", line)
-		case "error":
-			logger.Infof("%s", line)
+		case "error", "warning", "notice":
+			rc.addAnnotation(ctx, annotationFromCommand(command, kvPairs, arg))
 		case "add-mask":
 			rc.AddMask(arg)
 			logger.Infof("%s", "***")
@@ -76,6 +75,10 @@ func (rc *RunContext) commandHandler(ctx context.Context) common.LineHandler {
 			rc.saveState(ctx, kvPairs, arg)
 		case "add-matcher":
 			logger.Infof("%s", line)
+			rc.addMatcher(ctx, arg)
+		case "remove-matcher":
+			logger.Infof("%s", line)
+			rc.removeMatcher(ctx, kvPairs, arg)
 		default:
 			logger.Infof("%s", line)
 		}
@@ -187,3 +187,40 @@ func TestSaveState(t *testing.T) {
 
 	assert.Equal(t, "state-value", rc.IntraActionState["step"]["state-name"])
 }
+
+func TestAnnotationCommands(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	sources := make([]AnnotationSource, 0)
+	annotations := make([]*model.Annotation, 0)
+	rc := &RunContext{
+		Config: &Config{
+			AnnotationHandler: func(source AnnotationSource, annotation *model.Annotation) {
+				sources = append(sources, source)
+				annotations = append(annotations, annotation)
+			},
+			Secrets: map[string]string{"TOKEN": "s3cr3t"},
+		},
+		CurrentStep: "lint",
+		Matrix:      map[string]interface{}{"go": "1.20"},
+	}
+	handler := rc.commandHandler(ctx)
+
+	handler("::error file=app.js,line=1,col=5,endColumn=7,title=Lint%3A failed::Missing semicolon\n")
+	handler("::warning file=app.js,line=10,endLine=12::Unused variable%0Aremove it\n")
+	handler("::notice::Deployment skipped\n")
+	handler("##[error]Build failed\n")
+	handler("::debug::not an annotation\n")
+	handler("::error title=Token s3cr3t::Invalid token s3cr3t\n")
+
+	expected := []*model.Annotation{
+		{Level: model.AnnotationLevelError, Message: "Missing semicolon", Title: "Lint: failed", File: "app.js", Line: 1, Column: 5, EndColumn: 7},
+		{Level: model.AnnotationLevelWarning, Message: "Unused variable\nremove it", File: "app.js", Line: 10, EndLine: 12},
+		{Level: model.AnnotationLevelNotice, Message: "Deployment skipped"},
+		{Level: model.AnnotationLevelError, Message: "Build failed"},
+		{Level: model.AnnotationLevelError, Message: "Invalid token ***", Title: "Token ***"},
+	}
+	a.Equal(expected, rc.Annotations)
+	a.Equal(expected, annotations)
+	a.Equal(AnnotationSource{StepID: "lint", Matrix: map[string]interface{}{"go": "1.20"}}, sources[0])
+}
@@ -0,0 +1,247 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nektos/act/pkg/common"
+	"github.com/nektos/act/pkg/model"
+)
+
+// concurrencyGroups keeps track of the workflows and jobs running in each concurrency group,
+// a group has at most one running and one pending run, like on GitHub
+type concurrencyGroups struct {
+	mu     sync.Mutex
+	groups map[string]*concurrencyGroup
+}
+
+type concurrencyGroup struct {
+	running *concurrencySlot
+	pending *concurrencySlot
+}
+
+type concurrencySlot struct {
+	ready  chan struct{}
+	cancel context.CancelFunc
+}
+
+// errConcurrencyDeadlock is returned when a called workflow, or one of its jobs, waits for a group its caller holds
+var errConcurrencyDeadlock = errors.New("concurrency deadlock")
+
+type heldConcurrencyGroupsKey struct{}
+
+// withHeldConcurrencyGroup records the group as held by the runs in the context, including called workflows
+func withHeldConcurrencyGroup(ctx context.Context, group string) context.Context {
+	held := heldConcurrencyGroups(ctx)
+	groups := make(map[string]bool, len(held)+1)
+	for g := range held {
+		groups[g] = true
+	}
+	groups[group] = true
+	return context.WithValue(ctx, heldConcurrencyGroupsKey{}, groups)
+}
+
+func heldConcurrencyGroups(ctx context.Context) map[string]bool {
+	if groups, ok := ctx.Value(heldConcurrencyGroupsKey{}).(map[string]bool); ok {
+		return groups
+	}
+	return nil
+}
+
+func newConcurrencyGroups() *concurrencyGroups {
+	return &concurrencyGroups{
+		groups: make(map[string]*concurrencyGroup),
+	}
+}
+
+// acquire waits until the run may start in the group. The returned context is cancelled once
+// a newer run cancels this one, and release has to be called when the run has finished.
+// An already pending run of the group is cancelled, since only the newest run may wait.
+// Like on GitHub, a run may not wait for a group held by its caller, it would wait for or cancel itself.
+func (c *concurrencyGroups) acquire(ctx context.Context, group string, cancelInProgress bool) (context.Context, func(), error) {
+	if heldConcurrencyGroups(ctx)[group] {
+		return nil, nil, fmt.Errorf("canceling since a deadlock for concurrency group '%s' was detected between the run and the workflow or job holding it: %w", group, errConcurrencyDeadlock)
+	}
+	slotCtx, cancel := context.WithCancel(withHeldConcurrencyGroup(ctx, group))
+	slot := &concurrencySlot{
+		ready:  make(chan struct{}),
+		cancel: cancel,
+	}
+
+	c.mu.Lock()
+	g, ok := c.groups[group]
+	if !ok {
+		g = &concurrencyGroup{}
+		c.groups[group] = g
+	}
+	if g.pending != nil {
+		g.pending.cancel()
+		g.pending = nil
+	}
+	if g.running == nil {
+		g.running = slot
+		close(slot.ready)
+	} else {
+		if cancelInProgress {
+			g.running.cancel()
+		}
+		g.pending = slot
+	}
+	c.mu.Unlock()
+
+	release := func() {
+		c.release(group, slot)
+	}
+
+	select {
+	case <-slot.ready:
+		return slotCtx, release, nil
+	case <-slotCtx.Done():
+		release()
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("canceling since a higher priority waiting request for '%s' exists", group)
+	}
+}
+
+func (c *concurrencyGroups) release(group string, slot *concurrencySlot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer slot.cancel()
+
+	g, ok := c.groups[group]
+	if !ok {
+		return
+	}
+	switch slot {
+	case g.pending:
+		g.pending = nil
+	case g.running:
+		g.running = nil
+		if g.pending != nil {
+			g.running, g.pending = g.pending, nil
+			close(g.running.ready)
+		}
+	}
+	if g.running == nil && g.pending == nil {
+		delete(c.groups, group)
+	}
+}
+
+// evaluateConcurrency interpolates the group and cancel-in-progress of the concurrency,
+// an empty group is returned if the run is not limited
+func evaluateConcurrency(ctx context.Context, ee ExpressionEvaluator, concurrency *model.Concurrency) (string, bool, error) {
+	if concurrency == nil {
+		return "", false, nil
+	}
+	group := strings.TrimSpace(ee.Interpolate(ctx, concurrency.Group))
+	if group == "" {
+		return "", false, nil
+	}
+	cancelInProgress := false
+	if val := strings.TrimSpace(ee.Interpolate(ctx, concurrency.CancelInProgress)); val != "" {
+		var err error
+		if cancelInProgress, err = strconv.ParseBool(val); err != nil {
+			return "", false, fmt.Errorf("invalid value for 'cancel-in-progress' of concurrency group '%s': %s", group, val)
+		}
+	}
+	return group, cancelInProgress, nil
+}
+
+// acquireWorkflowConcurrency waits for the concurrency groups of the workflows in the plan.
+// The returned map holds the context every limited workflow has to run in, or nil if the workflow
+// has been cancelled while it was pending.
+func (runner *runnerImpl) acquireWorkflowConcurrency(ctx context.Context, plan *model.Plan) (map[*model.Workflow]context.Context, func(), error) {
+	type workflowGroup struct {
+		workflow         *model.Workflow
+		group            string
+		cancelInProgress bool
+	}
+
+	groups := make([]workflowGroup, 0)
+	seen := make(map[*model.Workflow]bool)
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			if seen[run.Workflow] {
+				continue
+			}
+			seen[run.Workflow] = true
+			rc := runner.newRunContext(ctx, run, nil)
+			group, cancelInProgress, err := evaluateConcurrency(ctx, rc.ExprEval, run.Workflow.Concurrency())
+			if err != nil {
+				return nil, nil, err
+			}
+			if group != "" {
+				groups = append(groups, workflowGroup{run.Workflow, group, cancelInProgress})
+			}
+		}
+	}
+	// acquire the groups in a fixed order, so that plans with several workflows can not deadlock
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].group < groups[j].group
+	})
+
+	workflowCtxs := make(map[*model.Workflow]context.Context)
+	releases := make([]func(), 0)
+	release := func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+	for _, g := range groups {
+		log.Debugf("Waiting for concurrency group '%s' of workflow '%s'", g.group, g.workflow.File)
+		workflowCtx, releaseGroup, err := runner.concurrency.acquire(ctx, g.group, g.cancelInProgress)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, errConcurrencyDeadlock) {
+				release()
+				return nil, nil, err
+			}
+			log.Infof("Workflow '%s' is cancelled: %v", g.workflow.Name, err)
+			workflowCtxs[g.workflow] = nil
+			continue
+		}
+		workflowCtxs[g.workflow] = workflowCtx
+		releases = append(releases, releaseGroup)
+	}
+	return workflowCtxs, release, nil
+}
+
+// newConcurrencyExecutor runs the job once the concurrency group of the job allows it
+func (rc *RunContext) newConcurrencyExecutor(executor common.Executor) common.Executor {
+	return func(ctx context.Context) error {
+		if rc.concurrency == nil {
+			return executor(ctx)
+		}
+		group, cancelInProgress, err := evaluateConcurrency(ctx, rc.ExprEval, rc.Run.Job().Concurrency())
+		if err != nil {
+			return err
+		}
+		if group == "" {
+			return executor(ctx)
+		}
+
+		logger := common.Logger(ctx)
+		logger.Debugf("Waiting for concurrency group '%s'", group)
+		groupCtx, release, err := rc.concurrency.acquire(ctx, group, cancelInProgress)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, errConcurrencyDeadlock) {
+				return err
+			}
+			logger.Infof("%v", err)
+			rc.cancelled = true
+			setJobResult(ctx, rc, rc, false)
+			return nil
+		}
+		defer release()
+
+		return executor(groupCtx)
+	}
+}
@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+func TestConcurrencyGroupsQueue(t *testing.T) {
+	groups := newConcurrencyGroups()
+
+	firstCtx, releaseFirst, err := groups.acquire(context.Background(), "deploy", false)
+	require.NoError(t, err)
+
+	acquired := make(chan error)
+	go func() {
+		ctx, release, err := groups.acquire(context.Background(), "deploy", false)
+		if err == nil {
+			assert.NoError(t, ctx.Err())
+			release()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("the second run must wait for the first one")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// other groups are not affected
+	_, releaseOther, err := groups.acquire(context.Background(), "other", false)
+	require.NoError(t, err)
+	releaseOther()
+
+	assert.NoError(t, firstCtx.Err(), "the running run must not be cancelled")
+	releaseFirst()
+	assert.NoError(t, <-acquired)
+	assert.Empty(t, groups.groups)
+}
+
+func TestConcurrencyGroupsReplacePending(t *testing.T) {
+	groups := newConcurrencyGroups()
+
+	_, releaseFirst, err := groups.acquire(context.Background(), "deploy", false)
+	require.NoError(t, err)
+
+	pending := make(chan error)
+	go func() {
+		_, _, err := groups.acquire(context.Background(), "deploy", false)
+		pending <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	newest := make(chan error)
+	go func() {
+		_, release, err := groups.acquire(context.Background(), "deploy", false)
+		if err == nil {
+			release()
+		}
+		newest <- err
+	}()
+
+	assert.ErrorContains(t, <-pending, "canceling since a higher priority waiting request for 'deploy' exists")
+	releaseFirst()
+	assert.NoError(t, <-newest)
+}
+
+func TestConcurrencyGroupsCancelInProgress(t *testing.T) {
+	groups := newConcurrencyGroups()
+
+	firstCtx, releaseFirst, err := groups.acquire(context.Background(), "deploy", false)
+	require.NoError(t, err)
+
+	acquired := make(chan error)
+	go func() {
+		_, release, err := groups.acquire(context.Background(), "deploy", true)
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case <-firstCtx.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("the running run must be cancelled")
+	}
+	// the newer run waits until the cancelled one has finished
+	releaseFirst()
+	assert.NoError(t, <-acquired)
+}
+
+func TestConcurrencyGroupsParentCancelled(t *testing.T) {
+	groups := newConcurrencyGroups()
+
+	_, releaseFirst, err := groups.acquire(context.Background(), "deploy", false)
+	require.NoError(t, err)
+	defer releaseFirst()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = groups.acquire(ctx, "deploy", false)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestConcurrencyGroupsDeadlock(t *testing.T) {
+	for _, cancelInProgress := range []bool{false, true} {
+		groups := newConcurrencyGroups()
+
+		callerCtx, releaseCaller, err := groups.acquire(context.Background(), "deploy", false)
+		require.NoError(t, err)
+
+		// a called workflow or job which uses the group of its caller would wait for or cancel the caller
+		_, _, err = groups.acquire(callerCtx, "deploy", cancelInProgress)
+		assert.ErrorIs(t, err, errConcurrencyDeadlock)
+		assert.NoError(t, callerCtx.Err(), "the caller must not be cancelled")
+
+		// other groups may still be acquired by the called workflow
+		_, releaseOther, err := groups.acquire(callerCtx, "other", cancelInProgress)
+		require.NoError(t, err)
+		releaseOther()
+
+		releaseCaller()
+		assert.Empty(t, groups.groups)
+	}
+}
+
+func TestAcquireWorkflowConcurrencyDeadlock(t *testing.T) {
+	workflow, err := model.ReadWorkflow(strings.NewReader(`
+name: called
+on: workflow_call
+concurrency: deploy-${{ github.ref }}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`))
+	require.NoError(t, err)
+	plan := &model.Plan{Stages: []*model.Stage{{Runs: []*model.Run{{JobID: "build", Workflow: workflow}}}}}
+
+	runner := &runnerImpl{config: &Config{Env: map[string]string{"GITHUB_REF": "refs/heads/main"}}, eventJSON: "{}"}
+	runner.concurrency = newConcurrencyGroups()
+
+	callerCtx, releaseCaller, err := runner.concurrency.acquire(context.Background(), "deploy-refs/heads/main", true)
+	require.NoError(t, err)
+	defer releaseCaller()
+
+	_, _, err = runner.acquireWorkflowConcurrency(callerCtx, plan)
+	assert.ErrorIs(t, err, errConcurrencyDeadlock)
+	assert.NoError(t, callerCtx.Err(), "the caller must not be cancelled")
+}
@@ -3,12 +3,20 @@ package runner
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/nektos/act/pkg/common"
 	"github.com/nektos/act/pkg/model"
 )
 
+const (
+	// defaultJobTimeout is the default `timeout-minutes` of a job on GitHub
+	defaultJobTimeout = 360 * time.Minute
+	// postStepsGracePeriod is the time the post steps of a cancelled or timed out job may still run
+	postStepsGracePeriod = 5 * time.Minute
+)
+
 type jobInfo interface {
 	matrix() map[string]interface{}
 	steps() []*model.Step
@@ -88,14 +96,17 @@ func newJobExecutor(info jobInfo, sf stepFactory, rc *RunContext) common.Executo
 		stepExec := step.main()
 		steps = append(steps, useStepLogger(rc, stepModel, stepStageMain, func(ctx context.Context) error {
 			logger := common.Logger(ctx)
+			startedAt := time.Now()
 			err := stepExec(ctx)
 			if err != nil {
 				logger.Errorf("%v", err)
 				common.SetJobError(ctx, err)
 			} else if ctx.Err() != nil {
-				logger.Errorf("%v", ctx.Err())
-				common.SetJobError(ctx, ctx.Err())
+				err = ctx.Err()
+				logger.Errorf("%v", err)
+				common.SetJobError(ctx, err)
 			}
+			rc.addStepExecution(ctx, stepModel, startedAt, err)
 			return nil
 		}))
 
@@ -145,6 +156,7 @@ func newJobExecutor(info jobInfo, sf stepFactory, rc *RunContext) common.Executo
 		}
 		setJobResult(ctx, info, rc, jobError == nil)
 		setJobOutputs(ctx, rc)
+		reportJobSummary(rc)
 
 		return err
 	})
@@ -153,19 +165,52 @@ func newJobExecutor(info jobInfo, sf stepFactory, rc *RunContext) common.Executo
 	pipeline = append(pipeline, preSteps...)
 	pipeline = append(pipeline, steps...)
 
-	return common.NewPipelineExecutor(info.startContainer(), common.NewPipelineExecutor(pipeline...).
-		Finally(func(ctx context.Context) error { //nolint:contextcheck
-			var cancel context.CancelFunc
-			if ctx.Err() == context.Canceled {
-				// in case of an aborted run, we still should execute the
-				// post steps to allow cleanup.
-				ctx, cancel = context.WithTimeout(common.WithLogger(context.Background(), common.Logger(ctx)), 5*time.Minute)
-				defer cancel()
+	return func(ctx context.Context) error {
+		timeoutCtx, cancelTimeout, timeout := evaluateJobTimeout(ctx, rc)
+		defer cancelTimeout()
+
+		err := common.NewPipelineExecutor(info.startContainer(), common.NewPipelineExecutor(pipeline...).
+			Finally(func(ctx context.Context) error { //nolint:contextcheck
+				var cancel context.CancelFunc
+				if ctx.Err() != nil {
+					if ctx.Err() == context.DeadlineExceeded {
+						timeoutErr := fmt.Errorf("the job has exceeded the maximum execution time of %v", timeout)
+						common.Logger(ctx).Errorf("%v", timeoutErr)
+						common.SetJobError(ctx, timeoutErr)
+					}
+					rc.cancelled = true
+					// in case of an aborted or timed out run, we still should execute the
+					// post steps to allow cleanup.
+					ctx, cancel = context.WithTimeout(common.WithJobErrorContainerOf(common.WithLogger(context.Background(), common.Logger(ctx)), ctx), postStepsGracePeriod)
+					defer cancel()
+				}
+				return postExecutor(ctx)
+			}).
+			Finally(info.interpolateOutputs()).
+			Finally(info.closeContainer()))(timeoutCtx)
+
+		if timeoutCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			return fmt.Errorf("job '%s' has exceeded the maximum execution time of %v", rc.JobName, timeout)
+		}
+		return err
+	}
+}
+
+// evaluateJobTimeout returns a context which is cancelled after the `timeout-minutes` of the job
+func evaluateJobTimeout(ctx context.Context, rc *RunContext) (context.Context, context.CancelFunc, time.Duration) {
+	timeout := defaultJobTimeout
+	// Have to be skipped for some Tests
+	if rc.Run != nil && rc.ExprEval != nil {
+		if timeoutMinutes := rc.ExprEval.Interpolate(ctx, rc.Run.Job().TimeoutMinutes); timeoutMinutes != "" {
+			if minutes, err := strconv.ParseFloat(timeoutMinutes, 64); err == nil && minutes > 0 {
+				timeout = time.Duration(minutes * float64(time.Minute))
+			} else {
+				common.Logger(ctx).Warnf("Invalid 'timeout-minutes' of job, using the default of %v: %s", defaultJobTimeout, timeoutMinutes)
 			}
-			return postExecutor(ctx)
-		}).
-		Finally(info.interpolateOutputs()).
-		Finally(info.closeContainer()))
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, timeout
 }
 
 func setJobResult(ctx context.Context, info jobInfo, rc *RunContext, success bool) {
@@ -178,7 +223,9 @@ func setJobResult(ctx context.Context, info jobInfo, rc *RunContext, success boo
 		jobResult = rc.Run.Job().Result
 	}
 
-	if !success {
+	if rc.cancelled && jobResult != "failure" {
+		jobResult = "cancelled"
+	} else if !success {
 		jobResult = "failure"
 	}
 
@@ -189,10 +236,16 @@ func setJobResult(ctx context.Context, info jobInfo, rc *RunContext, success boo
 	}
 
 	jobResultMessage := "succeeded"
-	if jobResult != "success" {
+	switch jobResult {
+	case "cancelled":
+		jobResultMessage = "cancelled"
+	case "failure":
 		jobResultMessage = "failed"
 	}
 
+	if len(rc.Annotations) > 0 {
+		logger = logger.WithField("annotations", rc.Annotations)
+	}
 	logger.WithField("jobResult", jobResult).Infof("\U0001F3C1  Job %s", jobResultMessage)
 }
 
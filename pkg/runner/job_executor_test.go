@@ -339,3 +339,69 @@ func TestNewJobExecutor(t *testing.T) {
 		})
 	}
 }
+
+func TestNewJobExecutorTimeout(t *testing.T) {
+	ctx := common.WithJobErrorContainer(context.Background())
+	jim := &jobInfoMock{}
+	sfm := &stepFactoryMock{}
+	rc := &RunContext{
+		JobContainer: &jobContainerMock{},
+		Run: &model.Run{
+			JobID: "test",
+			Workflow: &model.Workflow{
+				Jobs: map[string]*model.Job{
+					"test": {
+						TimeoutMinutes: "0.001",
+					},
+				},
+			},
+		},
+		Config: &Config{},
+	}
+	rc.ExprEval = rc.NewExpressionEvaluator(ctx)
+	executorOrder := make([]string, 0)
+
+	stepModel := &model.Step{ID: "1"}
+	sm := &stepMock{}
+	sfm.On("newStep", stepModel, rc).Return(sm, nil)
+	sm.On("pre").Return(func(ctx context.Context) error {
+		return nil
+	})
+	sm.On("main").Return(func(ctx context.Context) error {
+		executorOrder = append(executorOrder, "step1")
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	sm.On("post").Return(func(ctx context.Context) error {
+		executorOrder = append(executorOrder, "post1")
+		assert.NoError(t, ctx.Err(), "post steps should run within the grace period")
+		assert.True(t, rc.cancelled)
+		return nil
+	})
+
+	jim.On("steps").Return([]*model.Step{stepModel})
+	jim.On("matrix").Return(map[string]interface{}{})
+	jim.On("startContainer").Return(func(ctx context.Context) error {
+		executorOrder = append(executorOrder, "startContainer")
+		return nil
+	})
+	jim.On("interpolateOutputs").Return(func(ctx context.Context) error {
+		executorOrder = append(executorOrder, "interpolateOutputs")
+		return nil
+	})
+	jim.On("result", "cancelled")
+	jim.On("closeContainer").Return(func(ctx context.Context) error {
+		executorOrder = append(executorOrder, "closeContainer")
+		return nil
+	})
+
+	executor := newJobExecutor(jim, sfm, rc)
+	err := executor(ctx)
+	assert.ErrorContains(t, err, "has exceeded the maximum execution time")
+	assert.Equal(t, []string{"startContainer", "step1", "post1", "interpolateOutputs", "closeContainer"}, executorOrder)
+	assert.Equal(t, "cancelled", rc.getJobContext().Status)
+
+	jim.AssertExpectations(t)
+	sfm.AssertExpectations(t)
+	sm.AssertExpectations(t)
+}
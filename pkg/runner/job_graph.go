@@ -0,0 +1,171 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nektos/act/pkg/common"
+	"github.com/nektos/act/pkg/model"
+)
+
+// jobNode is a run of the plan together with its position in the dependency graph
+type jobNode struct {
+	run        *model.Run
+	dependants []*jobNode
+	pending    int  // number of needs which have not finished yet
+	blocked    bool // the run can never be started, because of a dependency cycle or a missing job
+}
+
+type jobNodeKey struct {
+	workflow *model.Workflow
+	jobID    string
+}
+
+// newJobGraph links every run of the plan to the runs of the same workflow it needs,
+// runs which cannot be scheduled because of a dependency cycle or a missing job are returned separately
+func newJobGraph(plan *model.Plan) ([]*jobNode, []*jobNode) {
+	nodes := make([]*jobNode, 0)
+	byKey := make(map[jobNodeKey]*jobNode)
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			key := jobNodeKey{run.Workflow, run.JobID}
+			if _, ok := byKey[key]; ok {
+				continue
+			}
+			node := &jobNode{run: run}
+			byKey[key] = node
+			nodes = append(nodes, node)
+		}
+	}
+
+	for _, node := range nodes {
+		for _, need := range node.run.Job().Needs() {
+			dep, ok := byKey[jobNodeKey{node.run.Workflow, need}]
+			if !ok {
+				log.Errorf("job '%s' needs job '%s', which is not part of the plan", node.run.JobID, need)
+				node.blocked = true
+				continue
+			}
+			dep.dependants = append(dep.dependants, node)
+			node.pending++
+		}
+	}
+
+	// walk the graph once to find the runs which can never become ready
+	pending := make(map[*jobNode]int, len(nodes))
+	queue := make([]*jobNode, 0)
+	for _, node := range nodes {
+		pending[node] = node.pending
+		if node.pending == 0 && !node.blocked {
+			queue = append(queue, node)
+		}
+	}
+	reachable := make(map[*jobNode]bool, len(nodes))
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		reachable[node] = true
+		for _, dependant := range node.dependants {
+			pending[dependant]--
+			if pending[dependant] == 0 && !dependant.blocked {
+				queue = append(queue, dependant)
+			}
+		}
+	}
+
+	schedulable := make([]*jobNode, 0, len(nodes))
+	unresolved := make([]*jobNode, 0)
+	for _, node := range nodes {
+		if reachable[node] {
+			schedulable = append(schedulable, node)
+		} else {
+			node.blocked = true
+			unresolved = append(unresolved, node)
+		}
+	}
+	return schedulable, unresolved
+}
+
+// newJobGraphExecutor starts every run of the plan as soon as all of its needs have finished,
+// running at most maxParallel runs at the same time
+func newJobGraphExecutor(plan *model.Plan, maxParallel int, newRunExecutor func(ctx context.Context, run *model.Run) common.Executor) common.Executor {
+	return func(ctx context.Context) error {
+		if 1 > maxParallel {
+			log.Debugf("Parallel jobs (%d) below minimum, setting to 1", maxParallel)
+			maxParallel = 1
+		}
+
+		nodes, unresolved := newJobGraph(plan)
+
+		var firstErr error
+		for _, node := range unresolved {
+			err := fmt.Errorf("unable to build dependency graph for %s (%s): job '%s' can not be scheduled", node.run.Workflow.Name, node.run.Workflow.File, node.run.JobID)
+			log.Error(err)
+			node.run.Job().Result = "failure"
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		type jobNodeResult struct {
+			node *jobNode
+			err  error
+		}
+
+		ready := make([]*jobNode, 0)
+		for _, node := range nodes {
+			if node.pending == 0 {
+				ready = append(ready, node)
+			}
+		}
+
+		done := make(chan jobNodeResult, len(nodes))
+		running := 0
+		for len(ready) > 0 || running > 0 {
+			for len(ready) > 0 && running < maxParallel && ctx.Err() == nil {
+				node := ready[0]
+				ready = ready[1:]
+				log.Debugf("Scheduling job '%s' of workflow '%s'", node.run.JobID, node.run.Workflow.File)
+				executor := newRunExecutor(ctx, node.run)
+				running++
+				go func() {
+					done <- jobNodeResult{node, executor(ctx)}
+				}()
+			}
+			if running == 0 {
+				// the plan has been cancelled, the remaining jobs are not started
+				break
+			}
+
+			result := <-done
+			running--
+
+			job := result.node.run.Job()
+			if result.err != nil {
+				if firstErr == nil {
+					firstErr = result.err
+				}
+				if job.Result == "" {
+					job.Result = "failure"
+				}
+			} else if job.Result == "" {
+				// the job has not been run, so its dependants see it as skipped
+				job.Result = "skipped"
+			}
+
+			for _, dependant := range result.node.dependants {
+				dependant.pending--
+				if dependant.pending == 0 && !dependant.blocked {
+					ready = append(ready, dependant)
+				}
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return firstErr
+	}
+}
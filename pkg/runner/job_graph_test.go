@@ -0,0 +1,235 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nektos/act/pkg/common"
+	"github.com/nektos/act/pkg/model"
+)
+
+func newJobGraphTestPlan(t *testing.T, workflow string) (*model.Workflow, *model.Plan) {
+	w, err := model.ReadWorkflow(strings.NewReader(workflow))
+	require.NoError(t, err)
+	w.File = "test.yml"
+
+	stage := &model.Stage{}
+	for _, jobID := range w.GetJobIDs() {
+		stage.Runs = append(stage.Runs, &model.Run{Workflow: w, JobID: jobID})
+	}
+	return w, &model.Plan{Stages: []*model.Stage{stage}}
+}
+
+func TestJobGraphExecutorStartsJobsWhenNeedsFinish(t *testing.T) {
+	w, plan := newJobGraphTestPlan(t, `
+on: push
+jobs:
+  slow:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo slow
+  fast:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo fast
+  after-fast:
+    needs: fast
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo after-fast
+  after-all:
+    needs: [slow, after-fast]
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo after-all
+`)
+
+	afterFastDone := make(chan struct{})
+	var mu sync.Mutex
+	order := make([]string, 0)
+
+	executor := newJobGraphExecutor(plan, 4, func(ctx context.Context, run *model.Run) common.Executor {
+		return func(ctx context.Context) error {
+			if run.JobID == "slow" {
+				// the dependants of other jobs must not wait for this one
+				select {
+				case <-afterFastDone:
+				case <-time.After(10 * time.Second):
+					t.Error("job 'after-fast' has not been started while job 'slow' was running")
+				}
+			}
+			mu.Lock()
+			order = append(order, run.JobID)
+			mu.Unlock()
+			run.Job().Result = "success"
+			if run.JobID == "after-fast" {
+				close(afterFastDone)
+			}
+			return nil
+		}
+	})
+
+	assert.NoError(t, executor(context.Background()))
+	assert.Equal(t, []string{"fast", "after-fast", "slow", "after-all"}, order)
+	for _, jobID := range w.GetJobIDs() {
+		assert.Equal(t, "success", w.GetJob(jobID).Result, jobID)
+	}
+}
+
+func TestJobGraphExecutorMaxParallel(t *testing.T) {
+	_, plan := newJobGraphTestPlan(t, `
+on: push
+jobs:
+  a:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo a
+  b:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo b
+  c:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo c
+`)
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+
+	executor := newJobGraphExecutor(plan, 2, func(ctx context.Context, run *model.Run) common.Executor {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		}
+	})
+
+	assert.NoError(t, executor(context.Background()))
+	assert.Equal(t, 2, maxRunning)
+}
+
+func TestJobGraphExecutorPropagatesResults(t *testing.T) {
+	w, plan := newJobGraphTestPlan(t, `
+on: push
+jobs:
+  skipped:
+    if: false
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo skipped
+  broken:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo broken
+  after-skipped:
+    needs: skipped
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo after-skipped
+  after-broken:
+    needs: broken
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo after-broken
+`)
+
+	needsResults := make(map[string]string)
+	var mu sync.Mutex
+
+	executor := newJobGraphExecutor(plan, 4, func(ctx context.Context, run *model.Run) common.Executor {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, need := range run.Job().Needs() {
+				needsResults[run.JobID] = w.GetJob(need).Result
+			}
+			switch run.JobID {
+			case "skipped":
+				return nil
+			case "broken":
+				return assert.AnError
+			}
+			run.Job().Result = "success"
+			return nil
+		}
+	})
+
+	assert.ErrorIs(t, executor(context.Background()), assert.AnError)
+	assert.Equal(t, map[string]string{
+		"after-skipped": "skipped",
+		"after-broken":  "failure",
+	}, needsResults)
+}
+
+func TestJobGraphExecutorCycle(t *testing.T) {
+	w, plan := newJobGraphTestPlan(t, `
+on: push
+jobs:
+  a:
+    needs: b
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo a
+  b:
+    needs: a
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo b
+  c:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo c
+  d:
+    needs: [c, missing]
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo d
+`)
+
+	var mu sync.Mutex
+	ran := make([]string, 0)
+
+	executor := newJobGraphExecutor(plan, 4, func(ctx context.Context, run *model.Run) common.Executor {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			ran = append(ran, run.JobID)
+			mu.Unlock()
+			run.Job().Result = "success"
+			return nil
+		}
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- executor(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "unable to build dependency graph")
+	case <-time.After(10 * time.Second):
+		t.Fatal("the job graph executor deadlocked")
+	}
+
+	assert.Equal(t, []string{"c"}, ran)
+	assert.Equal(t, "failure", w.GetJob("a").Result)
+	assert.Equal(t, "failure", w.GetJob("b").Result)
+	assert.Equal(t, "failure", w.GetJob("d").Result)
+}
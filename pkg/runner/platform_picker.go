@@ -0,0 +1,21 @@
+package runner
+
+import (
+	"github.com/nektos/act/pkg/model"
+)
+
+// NewPlatformPicker returns a platform picker for Config.RunsOnPicker which picks the image of a runner
+// of the group with the labels, such as `ubuntu-latest:docker://node:16-bullseye` or `linux:host`.
+// The jobs are matched against the group and the labels the same way a server routes them with model.SelectRunner.
+func NewPlatformPicker(group string, labels []string) (func(runsOn *model.RunsOn) string, error) {
+	runner, err := model.NewRunnerLabels(group, labels...)
+	if err != nil {
+		return nil, err
+	}
+	return func(runsOn *model.RunsOn) string {
+		if label := runner.Pick(runsOn); label != nil {
+			return label.Platform()
+		}
+		return ""
+	}, nil
+}
@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+func TestNewPlatformPicker(t *testing.T) {
+	pick, err := NewPlatformPicker("", []string{"ubuntu-latest:docker://node:16-bullseye", "self-hosted", "linux:host"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "node:16-bullseye", pick(&model.RunsOn{Labels: []string{"ubuntu-latest"}}))
+	assert.Equal(t, "-self-hosted", pick(&model.RunsOn{Labels: []string{"self-hosted", "linux"}}))
+	assert.Equal(t, "", pick(&model.RunsOn{Labels: []string{"ubuntu-latest", "gpu"}}))
+	assert.Equal(t, "", pick(&model.RunsOn{}))
+	assert.Equal(t, "", pick(&model.RunsOn{Group: "large", Labels: []string{"ubuntu-latest"}}))
+
+	_, err = NewPlatformPicker("", []string{"ubuntu-latest:vm:image"})
+	assert.Error(t, err)
+}
+
+func TestNewPlatformPickerGroup(t *testing.T) {
+	pick, err := NewPlatformPicker("large", []string{"ubuntu-latest:docker://node:16-bullseye", "linux:host"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "node:16-bullseye", pick(&model.RunsOn{Group: "large"}))
+	assert.Equal(t, "-self-hosted", pick(&model.RunsOn{Group: "Large", Labels: []string{"linux"}}))
+	assert.Equal(t, "node:16-bullseye", pick(&model.RunsOn{Labels: []string{"ubuntu-latest"}}))
+	assert.Equal(t, "", pick(&model.RunsOn{Group: "small", Labels: []string{"linux"}}))
+	assert.Equal(t, "", pick(&model.RunsOn{Labels: []string{"large"}}))
+}
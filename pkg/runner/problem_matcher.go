@@ -0,0 +1,304 @@
+package runner
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nektos/act/pkg/common"
+	"github.com/nektos/act/pkg/model"
+)
+
+// problemMatcherConfig is the content of a file registered with `add-matcher`
+type problemMatcherConfig struct {
+	ProblemMatcher []*problemMatcher `json:"problemMatcher"`
+}
+
+type problemMatcher struct {
+	Owner    string            `json:"owner"`
+	Severity string            `json:"severity"`
+	Pattern  []*problemPattern `json:"pattern"`
+
+	// the partial matches of the patterns of a multi-line matcher
+	state []*problemMatch
+}
+
+type problemPattern struct {
+	Regexp   string `json:"regexp"`
+	File     int    `json:"file"`
+	FromPath int    `json:"fromPath"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity int    `json:"severity"`
+	Code     int    `json:"code"`
+	Message  int    `json:"message"`
+	Loop     bool   `json:"loop"`
+
+	re *regexp.Regexp
+}
+
+type problemMatch struct {
+	file     string
+	fromPath string
+	line     string
+	column   string
+	severity string
+	code     string
+	message  string
+}
+
+// problemMatchers are the matchers registered in a job, they are applied to every line of step output
+type problemMatchers struct {
+	matchers []*problemMatcher
+}
+
+func parseProblemMatcherConfig(r io.Reader) (*problemMatcherConfig, error) {
+	config := &problemMatcherConfig{}
+	if err := json.NewDecoder(r).Decode(config); err != nil {
+		return nil, err
+	}
+	for _, matcher := range config.ProblemMatcher {
+		if err := matcher.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (m *problemMatcher) validate() error {
+	if m.Owner == "" {
+		return fmt.Errorf("problem matcher is missing the 'owner' property")
+	}
+	if len(m.Pattern) == 0 {
+		return fmt.Errorf("problem matcher '%s' has no patterns", m.Owner)
+	}
+	hasMessage := false
+	for i, pattern := range m.Pattern {
+		re, err := regexp.Compile(pattern.Regexp)
+		if err != nil {
+			return fmt.Errorf("problem matcher '%s' has an invalid regexp: %w", m.Owner, err)
+		}
+		pattern.re = re
+		if pattern.Loop && (len(m.Pattern) == 1 || i != len(m.Pattern)-1) {
+			return fmt.Errorf("problem matcher '%s': only the last pattern of a multi-line matcher may loop", m.Owner)
+		}
+		if pattern.Loop && pattern.Message == 0 {
+			return fmt.Errorf("problem matcher '%s': a looping pattern must capture the message", m.Owner)
+		}
+		if pattern.Message > 0 {
+			hasMessage = true
+		}
+	}
+	if !hasMessage {
+		return fmt.Errorf("problem matcher '%s' does not capture a message", m.Owner)
+	}
+	m.reset()
+	return nil
+}
+
+func (m *problemMatcher) reset() {
+	m.state = make([]*problemMatch, len(m.Pattern))
+}
+
+// match applies the line to the patterns, the patterns of a multi-line matcher have to match
+// consecutive lines and only the last pattern, which may repeat with `loop`, produces a match
+func (m *problemMatcher) match(line string) *problemMatch {
+	if len(m.Pattern) == 1 {
+		groups := m.Pattern[0].re.FindStringSubmatch(line)
+		if groups == nil {
+			return nil
+		}
+		return newProblemMatch(nil, m.Pattern[0], groups)
+	}
+
+	// iterate in reverse, so that a line is not matched by two patterns of the same run
+	for i := len(m.Pattern) - 1; i >= 0; i-- {
+		var running *problemMatch
+		if i > 0 {
+			running = m.state[i-1]
+			if running == nil {
+				continue
+			}
+		}
+		pattern := m.Pattern[i]
+		isLast := i == len(m.Pattern)-1
+		groups := pattern.re.FindStringSubmatch(line)
+
+		switch {
+		case groups != nil && isLast:
+			m.reset()
+			if pattern.Loop {
+				// keep the running match, so that the next line may match the loop again
+				m.state[i-1] = running
+			}
+			return newProblemMatch(running, pattern, groups)
+		case groups != nil:
+			m.state[i] = newProblemMatch(running, pattern, groups)
+		case isLast:
+			m.state[i-1] = nil
+		default:
+			m.state[i] = nil
+		}
+	}
+	return nil
+}
+
+func newProblemMatch(running *problemMatch, pattern *problemPattern, groups []string) *problemMatch {
+	match := &problemMatch{}
+	if running != nil {
+		*match = *running
+	}
+	group := func(index int, value *string) {
+		if index > 0 && index < len(groups) {
+			*value = groups[index]
+		}
+	}
+	group(pattern.File, &match.file)
+	group(pattern.FromPath, &match.fromPath)
+	group(pattern.Line, &match.line)
+	group(pattern.Column, &match.column)
+	group(pattern.Severity, &match.severity)
+	group(pattern.Code, &match.code)
+	group(pattern.Message, &match.message)
+	return match
+}
+
+// add registers the matchers of the config, replacing the matchers with the same owner
+func (pm *problemMatchers) add(config *problemMatcherConfig) {
+	for _, matcher := range config.ProblemMatcher {
+		pm.remove(matcher.Owner)
+		pm.matchers = append(pm.matchers, matcher)
+	}
+}
+
+func (pm *problemMatchers) remove(owner string) bool {
+	for i, matcher := range pm.matchers {
+		if strings.EqualFold(matcher.Owner, owner) {
+			pm.matchers = append(pm.matchers[:i], pm.matchers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the annotation of the first matcher which matches the line
+func (pm *problemMatchers) match(line string, workspace string) *model.Annotation {
+	line = strings.TrimRight(line, "\r\n")
+	for i, matcher := range pm.matchers {
+		match := matcher.match(line)
+		if match == nil || strings.TrimSpace(match.message) == "" {
+			continue
+		}
+		// a line is reported once, the other matchers start over
+		for j, other := range pm.matchers {
+			if j != i {
+				other.reset()
+			}
+		}
+		severity := match.severity
+		if severity == "" {
+			severity = matcher.Severity
+		}
+		annotation := &model.Annotation{
+			Level:   model.ParseAnnotationLevel(severity),
+			Message: strings.TrimSpace(match.message),
+			File:    problemMatchFile(match, workspace),
+			Code:    match.code,
+		}
+		annotation.Line, _ = strconv.Atoi(match.line)
+		annotation.Column, _ = strconv.Atoi(match.column)
+		return annotation
+	}
+	return nil
+}
+
+// problemMatchFile returns the file of the match relative to the workspace if possible
+func problemMatchFile(match *problemMatch, workspace string) string {
+	file := strings.ReplaceAll(match.file, "\\", "/")
+	if file == "" {
+		return ""
+	}
+	if !path.IsAbs(file) && match.fromPath != "" {
+		file = path.Join(path.Dir(strings.ReplaceAll(match.fromPath, "\\", "/")), file)
+	}
+	if path.IsAbs(file) && workspace != "" {
+		if rel := strings.TrimPrefix(file, strings.TrimSuffix(workspace, "/")+"/"); rel != file {
+			return rel
+		}
+	}
+	return path.Clean(file)
+}
+
+func (rc *RunContext) addMatcher(ctx context.Context, arg string) {
+	logger := common.Logger(ctx)
+	if rc.JobContainer == nil {
+		logger.Warnf("Unable to add problem matcher '%s': no job container", arg)
+		return
+	}
+	archive, err := rc.JobContainer.GetContainerArchive(ctx, arg)
+	if err != nil {
+		logger.Warnf("Unable to read problem matcher '%s': %v", arg, err)
+		return
+	}
+	defer archive.Close()
+
+	reader := tar.NewReader(archive)
+	if _, err := reader.Next(); err != nil {
+		logger.Warnf("Unable to read problem matcher '%s': %v", arg, err)
+		return
+	}
+	config, err := parseProblemMatcherConfig(reader)
+	if err != nil {
+		logger.Warnf("Unable to add problem matcher '%s': %v", arg, err)
+		return
+	}
+
+	jobRc := rc.jobRunContext()
+	if jobRc.problemMatchers == nil {
+		jobRc.problemMatchers = &problemMatchers{}
+	}
+	jobRc.problemMatchers.add(config)
+	for _, matcher := range config.ProblemMatcher {
+		logger.Infof("::add-matcher:: %s", matcher.Owner)
+	}
+}
+
+func (rc *RunContext) removeMatcher(ctx context.Context, kvPairs map[string]string, arg string) {
+	owner := kvPairs["owner"]
+	if owner == "" {
+		owner = arg
+	}
+	jobRc := rc.jobRunContext()
+	if jobRc.problemMatchers != nil && jobRc.problemMatchers.remove(owner) {
+		common.Logger(ctx).Infof("::remove-matcher:: %s", owner)
+	}
+}
+
+// matchProblems reports an annotation for the line of step output if a problem matcher matches it
+func (rc *RunContext) matchProblems(ctx context.Context, line string) {
+	jobRc := rc.jobRunContext()
+	if jobRc.problemMatchers == nil || len(jobRc.problemMatchers.matchers) == 0 {
+		return
+	}
+	workspace := ""
+	if rc.JobContainer != nil && rc.Config != nil {
+		workspace = rc.JobContainer.ToContainerPath(rc.Config.Workdir)
+	}
+	if annotation := jobRc.problemMatchers.match(line, workspace); annotation != nil {
+		rc.addAnnotation(ctx, annotation)
+	}
+}
+
+// jobRunContext returns the run context of the job, which the run context of a composite action belongs to
+func (rc *RunContext) jobRunContext() *RunContext {
+	for rc.Parent != nil {
+		rc = rc.Parent
+	}
+	return rc
+}
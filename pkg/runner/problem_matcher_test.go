@@ -0,0 +1,170 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nektos/act/pkg/common"
+	"github.com/nektos/act/pkg/container"
+	"github.com/nektos/act/pkg/model"
+)
+
+const goProblemMatcher = `{
+  "problemMatcher": [
+    {
+      "owner": "go",
+      "pattern": [
+        {
+          "regexp": "^\\s*(\\.{0,2}[\\/\\\\].+\\.go):(?:(\\d+):(\\d+):)? (.*)",
+          "file": 1,
+          "line": 2,
+          "column": 3,
+          "message": 4
+        }
+      ]
+    }
+  ]
+}`
+
+const eslintStylishProblemMatcher = `{
+  "problemMatcher": [
+    {
+      "owner": "eslint-stylish",
+      "pattern": [
+        {
+          "regexp": "^([^\\s].*)$",
+          "file": 1
+        },
+        {
+          "regexp": "^\\s+(\\d+):(\\d+)\\s+(error|warning|info)\\s+(.*)\\s\\s+(.*)$",
+          "line": 1,
+          "column": 2,
+          "severity": 3,
+          "message": 4,
+          "code": 5,
+          "loop": true
+        }
+      ]
+    }
+  ]
+}`
+
+func TestProblemMatchersSingleLine(t *testing.T) {
+	config, err := parseProblemMatcherConfig(strings.NewReader(goProblemMatcher))
+	require.NoError(t, err)
+
+	matchers := &problemMatchers{}
+	matchers.add(config)
+
+	assert.Nil(t, matchers.match("ok  \tgithub.com/nektos/act/pkg/model\t0.007s\n", "/workspace"))
+	assert.Equal(t, &model.Annotation{
+		Level:   model.AnnotationLevelError,
+		Message: "undefined: foo",
+		File:    "pkg/main.go",
+		Line:    12,
+		Column:  3,
+	}, matchers.match("/workspace/pkg/main.go:12:3: undefined: foo\n", "/workspace"))
+	assert.Equal(t, &model.Annotation{
+		Level:   model.AnnotationLevelError,
+		Message: "undefined: bar",
+		File:    "pkg/main.go",
+	}, matchers.match("./pkg/main.go: undefined: bar\n", "/workspace"))
+
+	assert.True(t, matchers.remove("go"))
+	assert.Nil(t, matchers.match("/workspace/pkg/main.go:12:3: undefined: foo\n", "/workspace"))
+}
+
+func TestProblemMatchersMultiLineLoop(t *testing.T) {
+	config, err := parseProblemMatcherConfig(strings.NewReader(eslintStylishProblemMatcher))
+	require.NoError(t, err)
+
+	matchers := &problemMatchers{}
+	matchers.add(config)
+
+	lines := []string{
+		"test.js",
+		"  1:0   error  Missing \"use strict\" statement                 strict",
+		"  5:10  warning  'addOne' is defined but never used         no-unused-vars",
+		"",
+		"other.js",
+		"  7:1   info  Unexpected console statement  no-console",
+		"",
+		"✖ 3 problems (1 error, 2 warnings)",
+	}
+	annotations := make([]*model.Annotation, 0)
+	for _, line := range lines {
+		if annotation := matchers.match(line, ""); annotation != nil {
+			annotations = append(annotations, annotation)
+		}
+	}
+
+	assert.Equal(t, []*model.Annotation{
+		{Level: model.AnnotationLevelError, Message: "Missing \"use strict\" statement", File: "test.js", Line: 1, Code: "strict"},
+		{Level: model.AnnotationLevelWarning, Message: "'addOne' is defined but never used", File: "test.js", Line: 5, Column: 10, Code: "no-unused-vars"},
+		{Level: model.AnnotationLevelError, Message: "Unexpected console statement", File: "other.js", Line: 7, Column: 1, Code: "no-console"},
+	}, annotations)
+}
+
+func TestProblemMatcherConfigValidation(t *testing.T) {
+	tables := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"missing owner", `{"problemMatcher":[{"pattern":[{"regexp":"(.*)","message":1}]}]}`, "missing the 'owner' property"},
+		{"no patterns", `{"problemMatcher":[{"owner":"x","pattern":[]}]}`, "has no patterns"},
+		{"invalid regexp", `{"problemMatcher":[{"owner":"x","pattern":[{"regexp":"(","message":1}]}]}`, "invalid regexp"},
+		{"single loop", `{"problemMatcher":[{"owner":"x","pattern":[{"regexp":"(.*)","message":1,"loop":true}]}]}`, "only the last pattern"},
+		{"no message", `{"problemMatcher":[{"owner":"x","pattern":[{"regexp":"(.*)","file":1}]}]}`, "does not capture a message"},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			_, err := parseProblemMatcherConfig(strings.NewReader(table.config))
+			assert.ErrorContains(t, err, table.err)
+		})
+	}
+}
+
+func TestAddMatcherCommand(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	ctx := common.WithLogger(context.Background(), logger)
+
+	matcherPath := filepath.Join(t.TempDir(), "go.json")
+	require.NoError(t, os.WriteFile(matcherPath, []byte(goProblemMatcher), 0o600))
+
+	parent := &RunContext{
+		JobContainer: &container.HostEnvironment{},
+		Config:       &Config{},
+	}
+	rc := &RunContext{
+		JobContainer: parent.JobContainer,
+		Config:       parent.Config,
+		Parent:       parent,
+	}
+	handler := rc.commandHandler(ctx)
+
+	handler("::add-matcher::" + matcherPath + "\n")
+	handler("./main.go:3:5: expected declaration\n")
+	assert.Equal(t, &model.Annotation{
+		Level:   model.AnnotationLevelError,
+		Message: "expected declaration",
+		File:    "main.go",
+		Line:    3,
+		Column:  5,
+	}, hook.LastEntry().Data["annotation"])
+
+	// matchers are registered for the whole job
+	assert.Len(t, parent.problemMatchers.matchers, 1)
+
+	handler("::remove-matcher owner=go::\n")
+	hook.Reset()
+	handler("./main.go:3:5: expected declaration\n")
+	assert.Empty(t, hook.AllEntries())
+}
@@ -186,6 +186,7 @@ func NewReusableWorkflowRunner(rc *RunContext) (Runner, error) {
 		caller: &caller{
 			runContext: rc,
 		},
+		concurrency: rc.concurrency,
 	}
 
 	return runner.configure()
@@ -48,14 +48,40 @@ type RunContext struct {
 	ActionPath          string
 	Parent              *RunContext
 	Masks               []string
+	Annotations         []*model.Annotation // the annotations reported by the steps of the job
+	Summary             string              // the Markdown written to GITHUB_STEP_SUMMARY by the steps of the job
 	cleanUpJobContainer common.Executor
-	caller              *caller // job calling this RunContext (reusable workflows)
+	caller              *caller                   // job calling this RunContext (reusable workflows)
+	cancelled           bool                      // the job has been cancelled or has timed out
+	concurrency         *concurrencyGroups        // the concurrency groups shared by all plans of the runner
+	problemMatchers     *problemMatchers          // the problem matchers registered in the job
+	stepExecutions      map[string]*stepExecution // the times and errors of the steps of the job
 }
 
 func (rc *RunContext) AddMask(mask string) {
 	rc.Masks = append(rc.Masks, mask)
 }
 
+// mask hides the secrets and the masked values in the text, like the job logger does
+func (rc *RunContext) mask(text string) string {
+	if rc.Config != nil {
+		if rc.Config.InsecureSecrets {
+			return text
+		}
+		for _, v := range rc.Config.Secrets {
+			if v != "" {
+				text = strings.ReplaceAll(text, v, "***")
+			}
+		}
+	}
+	for _, v := range rc.Masks {
+		if v != "" {
+			text = strings.ReplaceAll(text, v, "***")
+		}
+	}
+	return text
+}
+
 type MappableOutput struct {
 	StepID     string
 	OutputName string
@@ -656,7 +682,7 @@ func (rc *RunContext) Executor() (common.Executor, error) {
 			return err
 		}
 		if res {
-			return executor(ctx)
+			return rc.newConcurrencyExecutor(executor)(ctx)
 		}
 		return nil
 	}, nil
@@ -684,7 +710,11 @@ func (rc *RunContext) runsOnImage(ctx context.Context) string {
 		runsOn[i] = rc.ExprEval.Interpolate(ctx, v)
 	}
 
-	if pick := rc.Config.PlatformPicker; pick != nil {
+	if pick := rc.Config.RunsOnPicker; pick != nil {
+		if image := pick(rc.runsOnSpec(ctx)); image != "" {
+			return image
+		}
+	} else if pick := rc.Config.PlatformPicker; pick != nil {
 		if image := pick(runsOn); image != "" {
 			return image
 		}
@@ -700,6 +730,15 @@ func (rc *RunContext) runsOnImage(ctx context.Context) string {
 	return ""
 }
 
+// runsOnSpec returns the evaluated group and labels of `runs-on`
+func (rc *RunContext) runsOnSpec(ctx context.Context) *model.RunsOn {
+	job := rc.Run.Job()
+	if err := rc.ExprEval.EvaluateYamlNode(ctx, &job.RawRunsOn); err != nil {
+		common.Logger(ctx).Errorf("Error while evaluating runs-on: %v", err)
+	}
+	return job.RunsOnSpec()
+}
+
 func (rc *RunContext) runsOnPlatformNames(ctx context.Context) []string {
 	job := rc.Run.Job()
 
@@ -758,7 +797,9 @@ func (rc *RunContext) isEnabled(ctx context.Context) (bool, error) {
 
 	img := rc.platformImage(ctx)
 	if img == "" {
-		for _, platformName := range rc.runsOnPlatformNames(ctx) {
+		platformNames := rc.runsOnPlatformNames(ctx)
+		l.Infof("\U0001F6A7  Skipping job '%s': %v", job.Name, &model.NoRunnerError{RunsOn: rc.runsOnSpec(ctx)})
+		for _, platformName := range platformNames {
 			l.Infof("\U0001F6A7  Skipping unsupported platform -- Try running with `-P %+v=...`", platformName)
 		}
 		return false, nil
@@ -766,6 +807,20 @@ func (rc *RunContext) isEnabled(ctx context.Context) (bool, error) {
 	return true, nil
 }
 
+// isContinueOnError evaluates the `continue-on-error` of the job, a failed job with it does not fail the workflow
+func (rc *RunContext) isContinueOnError(ctx context.Context) (bool, error) {
+	expr := rc.Run.Job().RawContinueOnError
+	if len(strings.TrimSpace(expr)) == 0 {
+		return false, nil
+	}
+
+	continueOnError, err := EvalBool(ctx, rc.ExprEval, expr, exprparser.DefaultStatusCheckNone)
+	if err != nil {
+		return false, fmt.Errorf("  ❌  Error in continue-on-error-expression: \"continue-on-error: %s\" (%s)", expr, err)
+	}
+	return continueOnError, nil
+}
+
 func mergeMaps(maps ...map[string]string) map[string]string {
 	rtnMap := make(map[string]string)
 	for _, m := range maps {
@@ -824,6 +879,10 @@ func (rc *RunContext) getJobContext() *model.JobContext {
 			break
 		}
 	}
+	// a cancelled job takes precedence over failed steps
+	if rc.cancelled {
+		jobStatus = "cancelled"
+	}
 	return &model.JobContext{
 		Status: jobStatus,
 	}
@@ -1058,7 +1117,9 @@ func (rc *RunContext) withGithubEnv(ctx context.Context, github *model.GithubCon
 	}
 
 	if rc.Config.ArtifactServerPath != "" {
-		setActionRuntimeVars(rc, env)
+		setActionRuntimeVars(ctx, rc, github, env)
+	} else if rc.Config.Env["ACTIONS_CACHE_URL"] != "" {
+		setActionRuntimeToken(ctx, rc, github, env)
 	}
 
 	for _, platformName := range rc.runsOnPlatformNames(ctx) {
@@ -1076,20 +1137,59 @@ func (rc *RunContext) withGithubEnv(ctx context.Context, github *model.GithubCon
 	return env
 }
 
-func setActionRuntimeVars(rc *RunContext, env map[string]string) {
+func setActionRuntimeVars(ctx context.Context, rc *RunContext, github *model.GithubContext, env map[string]string) {
 	actionsRuntimeURL := os.Getenv("ACTIONS_RUNTIME_URL")
 	if actionsRuntimeURL == "" {
 		actionsRuntimeURL = fmt.Sprintf("http://%s:%s/", rc.Config.ArtifactServerAddr, rc.Config.ArtifactServerPort)
 	}
 	env["ACTIONS_RUNTIME_URL"] = actionsRuntimeURL
 
+	// actions/upload-artifact@v4 and actions/download-artifact@v4 use the artifact API v4 of the results service
+	actionsResultsURL := os.Getenv("ACTIONS_RESULTS_URL")
+	if actionsResultsURL == "" {
+		actionsResultsURL = actionsRuntimeURL
+	}
+	env["ACTIONS_RESULTS_URL"] = actionsResultsURL
+
+	setActionRuntimeToken(ctx, rc, github, env)
+}
+
+// setActionRuntimeToken sets ACTIONS_RUNTIME_TOKEN, the clients of the artifact API v4 read the ids of the run
+// and the job from it and the cache server reads the scope of the caches from it.
+// A token set by the embedder is kept, the cache server rejects it unless it is signed with the key
+// of common.SetAuthorizationTokenKey or the server is behind a proxy which scopes the requests.
+func setActionRuntimeToken(ctx context.Context, rc *RunContext, github *model.GithubContext, env map[string]string) {
 	actionsRuntimeToken := os.Getenv("ACTIONS_RUNTIME_TOKEN")
 	if actionsRuntimeToken == "" {
-		actionsRuntimeToken = "token"
+		actionsRuntimeToken = rc.Config.Env["ACTIONS_RUNTIME_TOKEN"]
+	}
+	if actionsRuntimeToken == "" {
+		token, err := common.CreateAuthorizationToken(github.RunID, rc.Run.JobID, cacheScope(github))
+		if err != nil {
+			common.Logger(ctx).Warnf("unable to create ACTIONS_RUNTIME_TOKEN: %v", err)
+			token = "token"
+		}
+		actionsRuntimeToken = token
 	}
 	env["ACTIONS_RUNTIME_TOKEN"] = actionsRuntimeToken
 }
 
+// cacheScope returns the scope of the caches of the run, the caches of a pull request are restored from
+// its base branch as well and the caches of all the runs from the default branch
+func cacheScope(github *model.GithubContext) *common.CacheScope {
+	scope := &common.CacheScope{
+		Repository: github.Repository,
+		Ref:        github.Ref,
+	}
+	if github.BaseRef != "" {
+		scope.RestoreRefs = append(scope.RestoreRefs, "refs/heads/"+github.BaseRef)
+	}
+	if defaultBranch, ok := nestedMapLookup(github.Event, "repository", "default_branch").(string); ok && defaultBranch != "" {
+		scope.RestoreRefs = append(scope.RestoreRefs, "refs/heads/"+defaultBranch)
+	}
+	return scope
+}
+
 func (rc *RunContext) handleCredentials(ctx context.Context) (string, string, error) {
 	// TODO: remove below 2 lines when we can release act with breaking changes
 	username := rc.Config.Secrets["DOCKER_USERNAME"]
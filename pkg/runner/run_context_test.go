@@ -10,11 +10,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/nektos/act/pkg/common"
 	"github.com/nektos/act/pkg/exprparser"
 	"github.com/nektos/act/pkg/model"
 
 	log "github.com/sirupsen/logrus"
 	assert "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	yaml "gopkg.in/yaml.v3"
 )
 
@@ -517,6 +519,27 @@ func TestRunContextRunsOnPlatformNames(t *testing.T) {
 	assertObject.Equal([]string{}, rc.runsOnPlatformNames(context.Background()))
 }
 
+func TestRunContextRunsOnPicker(t *testing.T) {
+	pick, err := NewPlatformPicker("large", []string{"ubuntu-latest:docker://node:16-bullseye"})
+	require.NoError(t, err)
+
+	rc := createIfTestRunContext(map[string]*model.Job{
+		"job1": createJob(t, `runs-on:
+  group: ${{ 'large' }}
+  labels: ubuntu-latest`, ""),
+	})
+	rc.Config.RunsOnPicker = pick
+	assert.Equal(t, &model.RunsOn{Group: "large", Labels: []string{"ubuntu-latest"}}, rc.runsOnSpec(context.Background()))
+	assert.Equal(t, "node:16-bullseye", rc.runsOnImage(context.Background()))
+
+	rc = createIfTestRunContext(map[string]*model.Job{
+		"job1": createJob(t, `runs-on:
+  group: small`, ""),
+	})
+	rc.Config.RunsOnPicker = pick
+	assert.Equal(t, "", rc.runsOnImage(context.Background()))
+}
+
 func TestRunContextIsEnabled(t *testing.T) {
 	log.SetLevel(log.DebugLevel)
 	assertObject := assert.New(t)
@@ -703,3 +726,41 @@ func Test_createSimpleContainerName(t *testing.T) {
 		})
 	}
 }
+
+func Test_cacheScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		github *model.GithubContext
+		want   *common.CacheScope
+	}{
+		{
+			name: "push",
+			github: &model.GithubContext{
+				Repository: "owner/repo",
+				Ref:        "refs/heads/feature",
+				Event:      map[string]interface{}{"repository": map[string]interface{}{"default_branch": "main"}},
+			},
+			want: &common.CacheScope{Repository: "owner/repo", Ref: "refs/heads/feature", RestoreRefs: []string{"refs/heads/main"}},
+		},
+		{
+			name: "pull request",
+			github: &model.GithubContext{
+				Repository: "owner/repo",
+				Ref:        "refs/pull/1/merge",
+				BaseRef:    "develop",
+				Event:      map[string]interface{}{"repository": map[string]interface{}{"default_branch": "main"}},
+			},
+			want: &common.CacheScope{Repository: "owner/repo", Ref: "refs/pull/1/merge", RestoreRefs: []string{"refs/heads/develop", "refs/heads/main"}},
+		},
+		{
+			name:   "no default branch",
+			github: &model.GithubContext{Repository: "owner/repo", Ref: "refs/heads/main"},
+			want:   &common.CacheScope{Repository: "owner/repo", Ref: "refs/heads/main"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, cacheScope(tt.github))
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package runner
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+// RunResult is the result of the execution of a plan, it contains the results of the workflows, jobs and steps
+type RunResult struct {
+	Workflows   []*WorkflowResult `json:"workflows"`
+	StartedAt   time.Time         `json:"startedAt"`
+	CompletedAt time.Time         `json:"completedAt"`
+	Error       string            `json:"error,omitempty"`
+
+	mu        sync.Mutex
+	workflows map[*model.Workflow]*WorkflowResult
+	runs      map[*model.Run]int // the order of the runs in the plan
+	completed map[*model.Run]bool
+}
+
+// WorkflowResult is the result of a workflow of the plan
+type WorkflowResult struct {
+	Name        string       `json:"name"`
+	File        string       `json:"file"`
+	Conclusion  string       `json:"conclusion"`
+	Outcome     string       `json:"outcome"`
+	StartedAt   time.Time    `json:"startedAt"`
+	CompletedAt time.Time    `json:"completedAt"`
+	Jobs        []*JobResult `json:"jobs"`
+}
+
+// JobResult is the result of a job, a matrix job has a result for every permutation
+type JobResult struct {
+	JobID       string                 `json:"jobId"`
+	Name        string                 `json:"name"`
+	Matrix      map[string]interface{} `json:"matrix,omitempty"`
+	Conclusion  string                 `json:"conclusion"`
+	Outcome     string                 `json:"outcome"`
+	Outputs     map[string]string      `json:"outputs,omitempty"`
+	StartedAt   time.Time              `json:"startedAt"`
+	CompletedAt time.Time              `json:"completedAt"`
+	Error       string                 `json:"error,omitempty"`
+	Annotations []*model.Annotation    `json:"annotations,omitempty"`
+	Summary     string                 `json:"summary,omitempty"`
+	Steps       []*StepResult          `json:"steps"`
+
+	run *model.Run
+}
+
+// StepResult is the result of the main stage of a step
+type StepResult struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Conclusion  string            `json:"conclusion"`
+	Outcome     string            `json:"outcome"`
+	Outputs     map[string]string `json:"outputs,omitempty"`
+	StartedAt   time.Time         `json:"startedAt"`
+	CompletedAt time.Time         `json:"completedAt"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// stepExecution is the time and error of the main stage of a step, see StepResult
+type stepExecution struct {
+	name        string
+	startedAt   time.Time
+	completedAt time.Time
+	err         error
+}
+
+func newRunResult(plan *model.Plan) *RunResult {
+	result := &RunResult{
+		Workflows: make([]*WorkflowResult, 0),
+		StartedAt: time.Now(),
+		workflows: make(map[*model.Workflow]*WorkflowResult),
+		runs:      make(map[*model.Run]int),
+		completed: make(map[*model.Run]bool),
+	}
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			result.runs[run] = len(result.runs)
+			if _, ok := result.workflows[run.Workflow]; ok {
+				continue
+			}
+			workflow := &WorkflowResult{
+				Name: run.Workflow.Name,
+				File: run.Workflow.File,
+				Jobs: make([]*JobResult, 0),
+			}
+			result.workflows[run.Workflow] = workflow
+			result.Workflows = append(result.Workflows, workflow)
+		}
+	}
+	return result
+}
+
+// addJob records the result of a job, or of a permutation of a matrix job, which has been executed
+func (r *RunResult) addJob(rc *RunContext, startedAt time.Time, err error, continueOnError bool) {
+	job := &JobResult{
+		JobID:       rc.Run.JobID,
+		Name:        rc.String(),
+		Matrix:      rc.Matrix,
+		Outcome:     jobOutcome(rc, err),
+		Outputs:     make(map[string]string),
+		StartedAt:   startedAt,
+		CompletedAt: time.Now(),
+		Annotations: rc.Annotations,
+		Summary:     rc.Summary,
+		Steps:       make([]*StepResult, 0),
+		run:         rc.Run,
+	}
+	job.Conclusion = job.Outcome
+	if job.Outcome == "failure" && continueOnError {
+		job.Conclusion = "success"
+	}
+	if err != nil {
+		job.Error = err.Error()
+	}
+	for k, v := range rc.Run.Job().Outputs {
+		job.Outputs[k] = rc.mask(v)
+	}
+
+	for _, stepModel := range rc.Run.Job().Steps {
+		if stepModel == nil {
+			continue
+		}
+		step := &StepResult{
+			ID:         stepModel.ID,
+			Name:       stepModel.String(),
+			Conclusion: model.StepStatusSkipped.String(),
+			Outcome:    model.StepStatusSkipped.String(),
+		}
+		if stepResult, ok := rc.StepResults[stepModel.ID]; ok {
+			step.Conclusion = stepResult.Conclusion.String()
+			step.Outcome = stepResult.Outcome.String()
+			step.Outputs = make(map[string]string, len(stepResult.Outputs))
+			for k, v := range stepResult.Outputs {
+				step.Outputs[k] = rc.mask(v)
+			}
+		}
+		if execution, ok := rc.stepExecutions[stepModel.ID]; ok {
+			step.Name = execution.name
+			step.StartedAt = execution.startedAt
+			step.CompletedAt = execution.completedAt
+			if execution.err != nil {
+				step.Error = execution.err.Error()
+			}
+		}
+		job.Steps = append(job.Steps, step)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.completed[rc.Run] = true
+	if workflow, ok := r.workflows[rc.Run.Workflow]; ok {
+		workflow.Jobs = append(workflow.Jobs, job)
+	}
+}
+
+// jobOutcome returns the result of the run context, which is a single permutation of a matrix job
+func jobOutcome(rc *RunContext, err error) string {
+	switch result := rc.Run.Job().Result; {
+	case rc.cancelled:
+		return "cancelled"
+	case err != nil:
+		return "failure"
+	case result == "" || result == "skipped":
+		return "skipped"
+	default:
+		return "success"
+	}
+}
+
+// complete adds the jobs which have not been executed and concludes the workflows
+func (r *RunResult) complete(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.CompletedAt = time.Now()
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	for run := range r.runs {
+		if r.completed[run] {
+			continue
+		}
+		outcome := run.Job().Result
+		if outcome == "" {
+			outcome = "skipped"
+		}
+		r.workflows[run.Workflow].Jobs = append(r.workflows[run.Workflow].Jobs, &JobResult{
+			JobID:      run.JobID,
+			Name:       run.String(),
+			Conclusion: outcome,
+			Outcome:    outcome,
+			Steps:      make([]*StepResult, 0),
+			run:        run,
+		})
+	}
+
+	for _, workflow := range r.Workflows {
+		sort.SliceStable(workflow.Jobs, func(i, j int) bool {
+			return r.runs[workflow.Jobs[i].run] < r.runs[workflow.Jobs[j].run]
+		})
+		conclusions := make([]string, 0, len(workflow.Jobs))
+		outcomes := make([]string, 0, len(workflow.Jobs))
+		for _, job := range workflow.Jobs {
+			conclusions = append(conclusions, job.Conclusion)
+			outcomes = append(outcomes, job.Outcome)
+			if !job.StartedAt.IsZero() && (workflow.StartedAt.IsZero() || job.StartedAt.Before(workflow.StartedAt)) {
+				workflow.StartedAt = job.StartedAt
+			}
+			if job.CompletedAt.After(workflow.CompletedAt) {
+				workflow.CompletedAt = job.CompletedAt
+			}
+		}
+		workflow.Conclusion = workflowResult(conclusions)
+		workflow.Outcome = workflowResult(outcomes)
+	}
+}
+
+// workflowResult combines the results of the jobs, a failure takes precedence over a cancellation and a success
+func workflowResult(results []string) string {
+	workflowResult := "skipped"
+	for _, result := range results {
+		switch {
+		case result == "failure":
+			return "failure"
+		case result == "cancelled":
+			workflowResult = "cancelled"
+		case result == "success" && workflowResult == "skipped":
+			workflowResult = "success"
+		}
+	}
+	return workflowResult
+}
+
+// addStepExecution records the time and error of the main stage of a step
+func (rc *RunContext) addStepExecution(ctx context.Context, stepModel *model.Step, startedAt time.Time, err error) {
+	if rc.stepExecutions == nil {
+		rc.stepExecutions = make(map[string]*stepExecution)
+	}
+	name := stepModel.String()
+	if rc.ExprEval != nil {
+		name = rc.ExprEval.Interpolate(ctx, name)
+	}
+	rc.stepExecutions[stepModel.ID] = &stepExecution{
+		name:        name,
+		startedAt:   startedAt,
+		completedAt: time.Now(),
+		err:         err,
+	}
+}
@@ -0,0 +1,194 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+func TestRunResult(t *testing.T) {
+	workflow := &model.Workflow{
+		Name: "test",
+		File: "test.yml",
+		Jobs: map[string]*model.Job{
+			"build": {
+				Steps: []*model.Step{
+					{ID: "checkout", Uses: "actions/checkout@v4"},
+					{ID: "build", Run: "make"},
+					{ID: "test", Run: "make test"},
+				},
+				Outputs: map[string]string{"version": "1.0.0"},
+				Result:  "failure",
+			},
+			"deploy": {Result: "skipped"},
+		},
+	}
+	build := &model.Run{JobID: "build", Workflow: workflow}
+	deploy := &model.Run{JobID: "deploy", Workflow: workflow}
+	plan := &model.Plan{Stages: []*model.Stage{
+		{Runs: []*model.Run{build}},
+		{Runs: []*model.Run{deploy}},
+	}}
+
+	result := newRunResult(plan)
+
+	newRunContext := func(experimental bool) *RunContext {
+		rc := &RunContext{
+			Config: &Config{Secrets: map[string]string{"TOKEN": "s3cr3t"}},
+			Name:   "build",
+			Run:    build,
+			Matrix: map[string]interface{}{"experimental": experimental},
+			StepResults: map[string]*model.StepResult{
+				"checkout": {Outcome: model.StepStatusSuccess, Conclusion: model.StepStatusSuccess, Outputs: map[string]string{"ref": "main", "token": "s3cr3t"}},
+				"build":    {Outcome: model.StepStatusFailure, Conclusion: model.StepStatusFailure, Outputs: map[string]string{}},
+			},
+			Annotations: []*model.Annotation{{Level: model.AnnotationLevelError, Message: "build failed"}},
+			Summary:     "### Build failed",
+		}
+		rc.addStepExecution(context.Background(), build.Job().Steps[0], time.Now(), nil)
+		rc.addStepExecution(context.Background(), build.Job().Steps[1], time.Now(), errors.New("exit code 2"))
+		return rc
+	}
+
+	startedAt := time.Now()
+	result.addJob(newRunContext(true), startedAt, errors.New("exit code 2"), true)
+	result.addJob(newRunContext(false), startedAt, errors.New("exit code 2"), false)
+	result.complete(errors.New("Job 'build' failed"))
+
+	require.Len(t, result.Workflows, 1)
+	assert.Equal(t, "Job 'build' failed", result.Error)
+
+	wf := result.Workflows[0]
+	assert.Equal(t, "test.yml", wf.File)
+	assert.Equal(t, "failure", wf.Conclusion)
+	assert.Equal(t, "failure", wf.Outcome)
+	assert.Equal(t, startedAt, wf.StartedAt)
+	require.Len(t, wf.Jobs, 3)
+
+	experimental := wf.Jobs[0]
+	assert.Equal(t, "build", experimental.JobID)
+	assert.Equal(t, "test/build", experimental.Name)
+	assert.Equal(t, map[string]interface{}{"experimental": true}, experimental.Matrix)
+	assert.Equal(t, "success", experimental.Conclusion)
+	assert.Equal(t, "failure", experimental.Outcome)
+	assert.Equal(t, "exit code 2", experimental.Error)
+	assert.Equal(t, map[string]string{"version": "1.0.0"}, experimental.Outputs)
+	assert.Equal(t, []*model.Annotation{{Level: model.AnnotationLevelError, Message: "build failed"}}, experimental.Annotations)
+	assert.Equal(t, "### Build failed", experimental.Summary)
+
+	require.Len(t, experimental.Steps, 3)
+	assert.Equal(t, "success", experimental.Steps[0].Conclusion)
+	assert.Equal(t, map[string]string{"ref": "main", "token": "***"}, experimental.Steps[0].Outputs)
+	assert.Equal(t, "actions/checkout@v4", experimental.Steps[0].Name)
+	assert.Equal(t, "failure", experimental.Steps[1].Outcome)
+	assert.Equal(t, "exit code 2", experimental.Steps[1].Error)
+	assert.False(t, experimental.Steps[1].CompletedAt.IsZero())
+	assert.Equal(t, "skipped", experimental.Steps[2].Conclusion)
+	assert.True(t, experimental.Steps[2].StartedAt.IsZero())
+
+	assert.Equal(t, "failure", wf.Jobs[1].Conclusion)
+
+	// a job which has not been executed takes the result of the plan
+	assert.Equal(t, "deploy", wf.Jobs[2].JobID)
+	assert.Equal(t, "skipped", wf.Jobs[2].Conclusion)
+	assert.Empty(t, wf.Jobs[2].Steps)
+
+	content, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"jobId":"build"`)
+	assert.Contains(t, string(content), `"conclusion":"skipped"`)
+}
+
+func TestWorkflowResult(t *testing.T) {
+	tables := []struct {
+		results []string
+		result  string
+	}{
+		{[]string{}, "skipped"},
+		{[]string{"skipped", "success"}, "success"},
+		{[]string{"success", "cancelled"}, "cancelled"},
+		{[]string{"cancelled", "failure", "success"}, "failure"},
+	}
+	for _, table := range tables {
+		t.Run(strings.Join(table.results, ","), func(t *testing.T) {
+			assert.Equal(t, table.result, workflowResult(table.results))
+		})
+	}
+}
+
+func TestRunPlanResult(t *testing.T) {
+	workflow, err := model.ReadWorkflow(strings.NewReader(`
+name: result
+on: push
+jobs:
+  disabled:
+    if: false
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 1
+`))
+	require.NoError(t, err)
+	workflow.File = "result.yml"
+	plan := &model.Plan{Stages: []*model.Stage{{Runs: []*model.Run{{JobID: "disabled", Workflow: workflow}}}}}
+
+	runner, err := New(&Config{Workdir: workdir, Platforms: platforms})
+	require.NoError(t, err)
+
+	result, err := runner.(PlanRunner).RunPlan(context.Background(), plan)
+	require.NoError(t, err)
+	require.Len(t, result.Workflows, 1)
+	require.Len(t, result.Workflows[0].Jobs, 1)
+	assert.Equal(t, "skipped", result.Workflows[0].Jobs[0].Conclusion)
+	assert.Equal(t, "skipped", result.Workflows[0].Conclusion)
+	assert.False(t, result.CompletedAt.Before(result.StartedAt))
+}
+
+func TestRunPlanResultFailedNeedsMatrix(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	workflow, err := model.ReadWorkflow(strings.NewReader(`
+name: result
+on: push
+jobs:
+  prepare:
+    runs-on: ubuntu-latest
+    outputs:
+      vals: ${{ steps.matrix.outputs.vals }}
+    steps:
+      - run: exit 1
+  build:
+    needs: prepare
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        val: ${{ fromJson(needs.prepare.outputs.vals) }}
+    steps:
+      - run: echo ${{ matrix.val }}
+`))
+	require.NoError(t, err)
+	workflow.File = "result.yml"
+	plan := &model.Plan{Stages: []*model.Stage{
+		{Runs: []*model.Run{{JobID: "prepare", Workflow: workflow}}},
+		{Runs: []*model.Run{{JobID: "build", Workflow: workflow}}},
+	}}
+
+	runner, err := New(&Config{Workdir: workdir, Platforms: map[string]string{"ubuntu-latest": "-self-hosted"}})
+	require.NoError(t, err)
+
+	// the matrix of a job whose needs have failed cannot be evaluated, the job is skipped instead
+	result, err := runner.(PlanRunner).RunPlan(context.Background(), plan)
+	assert.Error(t, err)
+	require.Len(t, result.Workflows, 1)
+	require.Len(t, result.Workflows[0].Jobs, 2)
+	assert.Equal(t, "failure", result.Workflows[0].Jobs[0].Conclusion)
+	assert.Equal(t, "skipped", result.Workflows[0].Jobs[1].Conclusion)
+}
@@ -3,9 +3,11 @@ package runner
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"runtime"
+	"sync"
 	"time"
 
 	docker_container "github.com/docker/docker/api/types/container"
@@ -20,6 +22,12 @@ type Runner interface {
 	NewPlanExecutor(plan *model.Plan) common.Executor
 }
 
+// PlanRunner is a Runner which returns the results of the plans it runs, the runners created by New implement it
+type PlanRunner interface {
+	Runner
+	RunPlan(ctx context.Context, plan *model.Plan) (*RunResult, error)
+}
+
 // Config contains the config for a new runner
 type Config struct {
 	Actor                              string                       // the user that triggered the event
@@ -64,15 +72,18 @@ type Config struct {
 	ContainerNetworkMode               docker_container.NetworkMode // the network mode of job containers (the value of --network)
 	ActionCache                        ActionCache                  // Use a custom ActionCache Implementation
 
-	PresetGitHubContext   *model.GithubContext         // the preset github context, overrides some fields like DefaultBranch, Env, Secrets etc.
-	EventJSON             string                       // the content of JSON file to use for event.json in containers, overrides EventPath
-	ContainerNamePrefix   string                       // the prefix of container name
-	ContainerMaxLifetime  time.Duration                // the max lifetime of job containers
-	DefaultActionInstance string                       // the default actions web site
-	PlatformPicker        func(labels []string) string // platform picker, it will take precedence over Platforms if isn't nil
-	JobLoggerLevel        *log.Level                   // the level of job logger
-	ValidVolumes          []string                     // only volumes (and bind mounts) in this slice can be mounted on the job container or service containers
-	InsecureSkipTLS       bool                         // whether to skip verifying TLS certificate of the Gitea instance
+	PresetGitHubContext   *model.GithubContext              // the preset github context, overrides some fields like DefaultBranch, Env, Secrets etc.
+	EventJSON             string                            // the content of JSON file to use for event.json in containers, overrides EventPath
+	ContainerNamePrefix   string                            // the prefix of container name
+	ContainerMaxLifetime  time.Duration                     // the max lifetime of job containers
+	DefaultActionInstance string                            // the default actions web site
+	PlatformPicker        func(labels []string) string      // platform picker, it will take precedence over Platforms if isn't nil
+	RunsOnPicker          func(runsOn *model.RunsOn) string // platform picker which gets the runner group of runs-on as well, it will take precedence over PlatformPicker if isn't nil
+	JobLoggerLevel        *log.Level                        // the level of job logger
+	ValidVolumes          []string                          // only volumes (and bind mounts) in this slice can be mounted on the job container or service containers
+	InsecureSkipTLS       bool                              // whether to skip verifying TLS certificate of the Gitea instance
+	AnnotationHandler     AnnotationHandler                 // receives the annotations reported by the steps of the jobs
+	JobSummaryHandler     JobSummaryHandler                 // receives the summary of every job which has written to GITHUB_STEP_SUMMARY
 }
 
 // GetToken: Adapt to Gitea
@@ -89,9 +100,10 @@ type caller struct {
 }
 
 type runnerImpl struct {
-	config    *Config
-	eventJSON string
-	caller    *caller // the job calling this runner (caller of a reusable workflow)
+	config      *Config
+	eventJSON   string
+	caller      *caller            // the job calling this runner (caller of a reusable workflow)
+	concurrency *concurrencyGroups // the concurrency groups shared by all plans of the runner
 }
 
 // New Creates a new Runner
@@ -104,6 +116,9 @@ func New(runnerConfig *Config) (Runner, error) {
 }
 
 func (runner *runnerImpl) configure() (Runner, error) {
+	if runner.concurrency == nil {
+		runner.concurrency = newConcurrencyGroups()
+	}
 	runner.eventJSON = "{}"
 	if runner.config.EventJSON != "" {
 		runner.eventJSON = runner.config.EventJSON
@@ -129,113 +144,230 @@ func (runner *runnerImpl) configure() (Runner, error) {
 
 // NewPlanExecutor ...
 func (runner *runnerImpl) NewPlanExecutor(plan *model.Plan) common.Executor {
+	return runner.newPlanExecutor(plan, newRunResult(plan))
+}
+
+// RunPlan executes the plan and returns the results of its workflows, jobs and steps
+func (runner *runnerImpl) RunPlan(ctx context.Context, plan *model.Plan) (*RunResult, error) {
+	result := newRunResult(plan)
+	err := runner.newPlanExecutor(plan, result)(ctx)
+	result.complete(err)
+	return result, err
+}
+
+func (runner *runnerImpl) newPlanExecutor(plan *model.Plan, result *RunResult) common.Executor {
 	maxJobNameLen := 0
+	continued := &continuedJobs{runs: make(map[*model.Run]bool)}
 
-	stagePipeline := make([]common.Executor, 0)
 	log.Debugf("Plan Stages: %v", plan.Stages)
 
-	for i := range plan.Stages {
-		stage := plan.Stages[i]
-		stagePipeline = append(stagePipeline, func(ctx context.Context) error {
-			pipeline := make([]common.Executor, 0)
-			for _, run := range stage.Runs {
-				log.Debugf("Stages Runs: %v", stage.Runs)
-				stageExecutor := make([]common.Executor, 0)
-				job := run.Job()
-				log.Debugf("Job.Name: %v", job.Name)
-				log.Debugf("Job.RawNeeds: %v", job.RawNeeds)
-				log.Debugf("Job.RawRunsOn: %v", job.RawRunsOn)
-				log.Debugf("Job.Env: %v", job.Env)
-				log.Debugf("Job.If: %v", job.If)
-				for step := range job.Steps {
-					if nil != job.Steps[step] {
-						log.Debugf("Job.Steps: %v", job.Steps[step].String())
-					}
-				}
-				log.Debugf("Job.TimeoutMinutes: %v", job.TimeoutMinutes)
-				log.Debugf("Job.Services: %v", job.Services)
-				log.Debugf("Job.Strategy: %v", job.Strategy)
-				log.Debugf("Job.RawContainer: %v", job.RawContainer)
-				log.Debugf("Job.Defaults.Run.Shell: %v", job.Defaults.Run.Shell)
-				log.Debugf("Job.Defaults.Run.WorkingDirectory: %v", job.Defaults.Run.WorkingDirectory)
-				log.Debugf("Job.Outputs: %v", job.Outputs)
-				log.Debugf("Job.Uses: %v", job.Uses)
-				log.Debugf("Job.With: %v", job.With)
-				// log.Debugf("Job.RawSecrets: %v", job.RawSecrets)
-				log.Debugf("Job.Result: %v", job.Result)
-
-				if job.Strategy != nil {
-					log.Debugf("Job.Strategy.FailFast: %v", job.Strategy.FailFast)
-					log.Debugf("Job.Strategy.MaxParallel: %v", job.Strategy.MaxParallel)
-					log.Debugf("Job.Strategy.FailFastString: %v", job.Strategy.FailFastString)
-					log.Debugf("Job.Strategy.MaxParallelString: %v", job.Strategy.MaxParallelString)
-					log.Debugf("Job.Strategy.RawMatrix: %v", job.Strategy.RawMatrix)
-
-					strategyRc := runner.newRunContext(ctx, run, nil)
-					if err := strategyRc.NewExpressionEvaluator(ctx).EvaluateYamlNode(ctx, &job.Strategy.RawMatrix); err != nil {
-						log.Errorf("Error while evaluating matrix: %v", err)
+	newRunExecutor := func(ctx context.Context, run *model.Run) common.Executor {
+		stageExecutor := make([]common.Executor, 0)
+		job := run.Job()
+		log.Debugf("Job.Name: %v", job.Name)
+		log.Debugf("Job.RawNeeds: %v", job.RawNeeds)
+		log.Debugf("Job.RawRunsOn: %v", job.RawRunsOn)
+		log.Debugf("Job.Env: %v", job.Env)
+		log.Debugf("Job.If: %v", job.If)
+		for step := range job.Steps {
+			if nil != job.Steps[step] {
+				log.Debugf("Job.Steps: %v", job.Steps[step].String())
+			}
+		}
+		log.Debugf("Job.TimeoutMinutes: %v", job.TimeoutMinutes)
+		log.Debugf("Job.RawContinueOnError: %v", job.RawContinueOnError)
+		log.Debugf("Job.Services: %v", job.Services)
+		log.Debugf("Job.Strategy: %v", job.Strategy)
+		log.Debugf("Job.RawContainer: %v", job.RawContainer)
+		log.Debugf("Job.Defaults.Run.Shell: %v", job.Defaults.Run.Shell)
+		log.Debugf("Job.Defaults.Run.WorkingDirectory: %v", job.Defaults.Run.WorkingDirectory)
+		log.Debugf("Job.Outputs: %v", job.Outputs)
+		log.Debugf("Job.Uses: %v", job.Uses)
+		log.Debugf("Job.With: %v", job.With)
+		// log.Debugf("Job.RawSecrets: %v", job.RawSecrets)
+		log.Debugf("Job.Result: %v", job.Result)
+
+		if job.Strategy != nil {
+			log.Debugf("Job.Strategy.FailFast: %v", job.Strategy.FailFast)
+			log.Debugf("Job.Strategy.MaxParallel: %v", job.Strategy.MaxParallel)
+			log.Debugf("Job.Strategy.FailFastString: %v", job.Strategy.FailFastString)
+			log.Debugf("Job.Strategy.MaxParallelString: %v", job.Strategy.MaxParallelString)
+			log.Debugf("Job.Strategy.RawMatrix: %v", job.Strategy.RawMatrix)
+
+			strategyRc := runner.newRunContext(ctx, run, nil)
+			if err := strategyRc.NewExpressionEvaluator(ctx).EvaluateYamlNode(ctx, &job.Strategy.RawMatrix); err != nil {
+				log.Errorf("Error while evaluating matrix: %v", err)
+				// the matrix cannot be decoded, which is expected for a job skipped because its needs have failed
+				return func(ctx context.Context) error {
+					if enabled, ifErr := strategyRc.isEnabled(ctx); ifErr == nil && !enabled {
+						return nil
 					}
+					err = fmt.Errorf("unable to evaluate the matrix of job '%s': %w", run.JobID, err)
+					result.addJob(strategyRc, time.Now(), err, false)
+					return err
 				}
+			}
+		}
 
-				var matrixes []map[string]interface{}
-				if m, err := job.GetMatrixes(); err != nil {
-					log.Errorf("Error while get job's matrix: %v", err)
-				} else {
-					log.Debugf("Job Matrices: %v", m)
-					log.Debugf("Runner Matrices: %v", runner.config.Matrix)
-					matrixes = selectMatrixes(m, runner.config.Matrix)
-				}
-				log.Debugf("Final matrix after applying user inclusions '%v'", matrixes)
+		var matrixes []map[string]interface{}
+		if m, err := job.GetMatrixes(); err != nil {
+			log.Errorf("Error while get job's matrix: %v", err)
+		} else {
+			log.Debugf("Job Matrices: %v", m)
+			log.Debugf("Runner Matrices: %v", runner.config.Matrix)
+			matrixes = selectMatrixes(m, runner.config.Matrix)
+		}
+		log.Debugf("Final matrix after applying user inclusions '%v'", matrixes)
+
+		maxParallel := 4
+		if job.Strategy != nil {
+			maxParallel = job.Strategy.MaxParallel
+		}
+
+		if len(matrixes) < maxParallel {
+			maxParallel = len(matrixes)
+		}
+
+		// with fail-fast, the first failing permutation cancels the running and queued siblings
+		failFastCtx, cancelFailFast := context.WithCancel(ctx)
+		failFast := job.Strategy != nil && job.Strategy.FailFast && len(matrixes) > 1
 
-				maxParallel := 4
-				if job.Strategy != nil {
-					maxParallel = job.Strategy.MaxParallel
+		// the name length is captured, since other jobs may be scheduled while this one runs
+		var jobNameLen int
+		for i, matrix := range matrixes {
+			matrix := matrix
+			rc := runner.newRunContext(ctx, run, matrix)
+			rc.JobName = rc.Name
+			if len(matrixes) > 1 {
+				rc.Name = fmt.Sprintf("%s-%d", rc.Name, i+1)
+			}
+			if len(rc.String()) > maxJobNameLen {
+				maxJobNameLen = len(rc.String())
+			}
+			stageExecutor = append(stageExecutor, func(ctx context.Context) error {
+				jobName := fmt.Sprintf("%-*s", jobNameLen, rc.String())
+				executor, err := rc.Executor()
+
+				if err != nil {
+					return err
 				}
 
-				if len(matrixes) < maxParallel {
-					maxParallel = len(matrixes)
+				jobCtx := common.WithJobErrorContainer(WithJobLogger(failFastCtx, rc.Run.JobID, jobName, rc.Config, &rc.Masks, matrix))
+				if failFast && failFastCtx.Err() != nil && ctx.Err() == nil {
+					// a sibling has already failed, so the queued job is cancelled without being started
+					common.Logger(jobCtx).Infof("Skipping job %s, since fail-fast is enabled and a matrix job has failed", rc.String())
+					rc.cancelled = true
+					setJobResult(jobCtx, rc, rc, false)
+					result.addJob(rc, time.Now(), nil, false)
+					return nil
 				}
 
-				for i, matrix := range matrixes {
-					matrix := matrix
-					rc := runner.newRunContext(ctx, run, matrix)
-					rc.JobName = rc.Name
-					if len(matrixes) > 1 {
-						rc.Name = fmt.Sprintf("%s-%d", rc.Name, i+1)
-					}
-					if len(rc.String()) > maxJobNameLen {
-						maxJobNameLen = len(rc.String())
+				startedAt := time.Now()
+				err = executor(jobCtx)
+				jobErr := err
+				if jobErr == nil {
+					jobErr = common.JobError(jobCtx)
+				}
+				continueOnError := false
+				if failFast && errors.Is(err, context.Canceled) && failFastCtx.Err() != nil && ctx.Err() == nil {
+					// cancelled by a failing sibling, the result has already been recorded
+					err = nil
+				} else if jobErr != nil {
+					continueOnError = continued.add(jobCtx, rc, err)
+					if continueOnError {
+						err = nil
+					} else if failFast {
+						cancelFailFast()
 					}
-					stageExecutor = append(stageExecutor, func(ctx context.Context) error {
-						jobName := fmt.Sprintf("%-*s", maxJobNameLen, rc.String())
-						executor, err := rc.Executor()
+				}
+				result.addJob(rc, startedAt, jobErr, continueOnError)
+				return err
+			})
+		}
+		jobNameLen = maxJobNameLen
+		return common.NewParallelExecutor(maxParallel, stageExecutor...).Finally(func(ctx context.Context) error {
+			cancelFailFast()
+			return nil
+		})
+	}
 
-						if err != nil {
-							return err
-						}
+	ncpu := runtime.NumCPU()
+	if 1 > ncpu {
+		ncpu = 1
+	}
+	log.Debugf("Detected CPUs: %d", ncpu)
+
+	return common.Executor(func(ctx context.Context) error {
+		workflowCtxs, release, err := runner.acquireWorkflowConcurrency(ctx, plan)
+		if err != nil {
+			return err
+		}
+		defer release()
 
-						return executor(common.WithJobErrorContainer(WithJobLogger(ctx, rc.Run.JobID, jobName, rc.Config, &rc.Masks, matrix)))
-					})
+		return newJobGraphExecutor(plan, ncpu, func(ctx context.Context, run *model.Run) common.Executor {
+			workflowCtx, ok := workflowCtxs[run.Workflow]
+			if !ok {
+				return newRunExecutor(ctx, run)
+			}
+			if workflowCtx == nil {
+				// the workflow has been cancelled by a newer run of its concurrency group
+				return func(ctx context.Context) error {
+					run.Job().Result = "cancelled"
+					return nil
 				}
-				pipeline = append(pipeline, common.NewParallelExecutor(maxParallel, stageExecutor...))
 			}
-			ncpu := runtime.NumCPU()
-			if 1 > ncpu {
-				ncpu = 1
+			executor := newRunExecutor(workflowCtx, run)
+			return func(ctx context.Context) error {
+				return executor(workflowCtx)
 			}
-			log.Debugf("Detected CPUs: %d", ncpu)
-			return common.NewParallelExecutor(ncpu, pipeline...)(ctx)
-		})
+		})(ctx)
+	}).Then(handleFailure(plan, continued))
+}
+
+// continuedJobs are the jobs whose failed permutations all have `continue-on-error`
+type continuedJobs struct {
+	mu   sync.Mutex
+	runs map[*model.Run]bool
+}
+
+// add records the failure of the job, it returns true if the job continues on error
+func (c *continuedJobs) add(ctx context.Context, rc *RunContext, err error) bool {
+	logger := common.Logger(ctx)
+	continueOnError, evalErr := rc.isContinueOnError(ctx)
+	if evalErr != nil {
+		logger.Errorf("%v", evalErr)
 	}
 
-	return common.NewPipelineExecutor(stagePipeline...).Then(handleFailure(plan))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !continueOnError {
+		c.runs[rc.Run] = false
+		return false
+	}
+	if _, ok := c.runs[rc.Run]; !ok {
+		c.runs[rc.Run] = true
+	}
+
+	if err != nil {
+		logger.Errorf("%v", err)
+		// the job has not been able to record its result
+		rc.result("failure")
+	}
+	logger.Warnf("Job %s failed, but continues on error", rc.String())
+	return true
 }
 
-func handleFailure(plan *model.Plan) common.Executor {
+func (c *continuedJobs) has(run *model.Run) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.runs[run]
+}
+
+func handleFailure(plan *model.Plan, continued *continuedJobs) common.Executor {
 	return func(ctx context.Context) error {
 		for _, stage := range plan.Stages {
 			for _, run := range stage.Runs {
-				if run.Job().Result == "failure" {
+				if run.Job().Result == "failure" && !continued.has(run) {
 					return fmt.Errorf("Job '%s' failed", run.String())
 				}
 			}
@@ -263,14 +395,40 @@ func selectMatrixes(originalMatrixes []map[string]interface{}, targetMatrixValue
 	return matrixes
 }
 
+// withScheduleEvent sets `github.event.schedule` to the first schedule of the workflow
+// for simulated runs of the `schedule` event which have no schedule in the event
+func withScheduleEvent(eventName, eventJSON string, workflow *model.Workflow) string {
+	if eventName != "schedule" || workflow == nil {
+		return eventJSON
+	}
+	schedules := workflow.OnSchedule()
+	if len(schedules) == 0 {
+		return eventJSON
+	}
+	event := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(eventJSON), &event); err != nil {
+		return eventJSON
+	}
+	if _, ok := event["schedule"]; ok {
+		return eventJSON
+	}
+	event["schedule"] = schedules[0]
+	content, err := json.Marshal(event)
+	if err != nil {
+		return eventJSON
+	}
+	return string(content)
+}
+
 func (runner *runnerImpl) newRunContext(ctx context.Context, run *model.Run, matrix map[string]interface{}) *RunContext {
 	rc := &RunContext{
 		Config:      runner.config,
 		Run:         run,
-		EventJSON:   runner.eventJSON,
+		EventJSON:   withScheduleEvent(runner.config.EventName, runner.eventJSON, run.Workflow),
 		StepResults: make(map[string]*model.StepResult),
 		Matrix:      matrix,
 		caller:      runner.caller,
+		concurrency: runner.concurrency,
 	}
 	rc.ExprEval = rc.NewExpressionEvaluator(ctx)
 	rc.Name = rc.ExprEval.Interpolate(ctx, run.String())
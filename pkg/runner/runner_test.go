@@ -11,10 +11,12 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
 	assert "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 
 	"github.com/nektos/act/pkg/common"
@@ -272,6 +274,8 @@ func TestRunEvent(t *testing.T) {
 		{workdir, "matrix", "push", "", platforms, secrets},
 		{workdir, "matrix-include-exclude", "push", "", platforms, secrets},
 		{workdir, "matrix-exitcode", "push", "Job 'test' failed", platforms, secrets},
+		{workdir, "matrix-fail-fast", "push", "Job 'test' failed", platforms, secrets},
+		{workdir, "job-continue-on-error", "push", "", platforms, secrets},
 		{workdir, "commands", "push", "", platforms, secrets},
 		{workdir, "workdir", "push", "", platforms, secrets},
 		{workdir, "defaults-run", "push", "", platforms, secrets},
@@ -682,3 +686,98 @@ func TestRunMatrixWithUserDefinedInclusions(t *testing.T) {
 
 	tjfi.runTest(context.Background(), t, &Config{Matrix: matrix})
 }
+
+func TestHandleFailureContinueOnError(t *testing.T) {
+	ctx := context.Background()
+
+	workflow := &model.Workflow{
+		Name: "test",
+		Jobs: map[string]*model.Job{
+			"test": {
+				RawContinueOnError: "${{ matrix.experimental }}",
+				Result:             "failure",
+			},
+		},
+	}
+	run := &model.Run{JobID: "test", Workflow: workflow}
+	plan := &model.Plan{Stages: []*model.Stage{{Runs: []*model.Run{run}}}}
+
+	runner := &runnerImpl{config: &Config{}}
+	newRunContext := func(experimental bool) *RunContext {
+		return runner.newRunContext(ctx, run, map[string]interface{}{"experimental": experimental})
+	}
+
+	continued := &continuedJobs{runs: make(map[*model.Run]bool)}
+	assert.True(t, continued.add(ctx, newRunContext(true), nil))
+	assert.NoError(t, handleFailure(plan, continued)(ctx))
+	assert.Equal(t, "failure", run.Job().Result)
+
+	// a permutation without continue-on-error fails the workflow
+	assert.False(t, continued.add(ctx, newRunContext(false), nil))
+	assert.True(t, continued.add(ctx, newRunContext(true), nil))
+	assert.EqualError(t, handleFailure(plan, continued)(ctx), "Job 'test' failed")
+}
+
+func TestWithScheduleEvent(t *testing.T) {
+	workflow, err := model.ReadWorkflow(strings.NewReader(`
+on:
+  schedule:
+    - cron: '30 2 * * *'
+    - cron: '0 12 * * 0'
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo ${{ github.event.schedule }}
+`))
+	assert.NoError(t, err)
+
+	assert.JSONEq(t, `{"schedule":"30 2 * * *"}`, withScheduleEvent("schedule", "{}", workflow))
+	assert.JSONEq(t, `{"schedule":"0 12 * * 0"}`, withScheduleEvent("schedule", `{"schedule":"0 12 * * 0"}`, workflow))
+	assert.JSONEq(t, `{}`, withScheduleEvent("push", "{}", workflow))
+}
+
+func TestRunMatrixFailFast(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("the workflows use bash")
+	}
+
+	tables := []struct {
+		workflowPath string
+		conclusions  map[string]string // the conclusion of every permutation by the value of matrix.val
+	}{
+		{"matrix-fail-fast", map[string]string{"failure": "failure", "cancelled": "cancelled"}},
+		{"matrix-no-fail-fast", map[string]string{"failure": "failure", "success": "success"}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.workflowPath, func(t *testing.T) {
+			planner, err := model.NewWorkflowPlanner(filepath.Join(workdir, table.workflowPath), true)
+			require.NoError(t, err)
+			plan, err := planner.PlanEvent("push")
+			require.NoError(t, err)
+
+			runner, err := New(&Config{
+				Workdir:   workdir,
+				EventName: "push",
+				Platforms: map[string]string{"ubuntu-latest": "-self-hosted"},
+			})
+			require.NoError(t, err)
+
+			result, err := runner.(PlanRunner).RunPlan(context.Background(), plan)
+			assert.EqualError(t, err, "Job 'test' failed")
+			require.Len(t, result.Workflows, 1)
+
+			conclusions := map[string]string{}
+			for _, job := range result.Workflows[0].Jobs {
+				conclusions[fmt.Sprint(job.Matrix["val"])] = job.Conclusion
+			}
+			assert.Equal(t, table.conclusions, conclusions)
+			// the slow permutation of matrix-fail-fast sleeps for a minute unless it is cancelled
+			assert.Less(t, result.CompletedAt.Sub(result.StartedAt), 30*time.Second)
+		})
+	}
+}
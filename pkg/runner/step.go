@@ -182,6 +182,9 @@ func runStepExecutor(step step, stage stepStage, executor common.Executor) commo
 		if err != nil {
 			return err
 		}
+		if err := rc.collectStepSummary(ctx, actPath, summaryFileCommand); err != nil {
+			logger.Warnf("Unable to read the summary of step '%s': %v", stepModel, err)
+		}
 		if orgerr != nil {
 			return orgerr
 		}
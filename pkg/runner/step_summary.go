@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/nektos/act/pkg/common"
+	"github.com/nektos/act/pkg/container"
+)
+
+// JobSummaryHandler receives the Markdown summary of a job, which is combined from the GITHUB_STEP_SUMMARY files of its steps
+type JobSummaryHandler func(jobID, jobName, summary string)
+
+// maxStepSummarySize is the maximum size of the summary of a step, like on GitHub
+const maxStepSummarySize = 1024 * 1024
+
+// collectStepSummary adds the content of the GITHUB_STEP_SUMMARY file of a step to the summary of the job
+func (rc *RunContext) collectStepSummary(ctx context.Context, actPath string, summaryFileCommand string) error {
+	if common.Dryrun(ctx) {
+		return nil
+	}
+	logger := common.Logger(ctx)
+
+	summaryTar, err := rc.JobContainer.GetContainerArchive(ctx, actPath+"/"+summaryFileCommand)
+	if err != nil {
+		return err
+	}
+	defer summaryTar.Close()
+
+	reader := tar.NewReader(summaryTar)
+	header, err := reader.Next()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if header.Size == 0 {
+		return nil
+	}
+	if header.Size > maxStepSummarySize {
+		logger.Warnf("The summary of step '%s' is %d bytes, which exceeds the limit of %d bytes, it is not added to the job summary", rc.CurrentStep, header.Size, maxStepSummarySize)
+	} else {
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		summary := rc.mask(string(content))
+
+		jobRc := rc.jobRunContext()
+		if jobRc.Summary != "" && !strings.HasSuffix(jobRc.Summary, "\n") {
+			jobRc.Summary += "\n"
+		}
+		jobRc.Summary += summary
+	}
+
+	// clear the file, so that a step of a composite action is not added again by the calling step
+	return rc.JobContainer.Copy(actPath, &container.FileEntry{
+		Name: summaryFileCommand,
+		Mode: 0o666,
+	})(ctx)
+}
+
+// reportJobSummary passes the summary of the job to the handler of the config
+func reportJobSummary(rc *RunContext) {
+	if rc.Summary == "" || rc.Config == nil || rc.Config.JobSummaryHandler == nil {
+		return
+	}
+	rc.Config.JobSummaryHandler(rc.Run.JobID, rc.String(), rc.Summary)
+}
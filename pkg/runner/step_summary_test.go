@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nektos/act/pkg/container"
+	"github.com/nektos/act/pkg/model"
+)
+
+func TestCollectStepSummary(t *testing.T) {
+	ctx := context.Background()
+
+	actPath := t.TempDir()
+	summaryPath := filepath.Join(actPath, "workflow", "SUMMARY.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(summaryPath), 0o777))
+
+	parent := &RunContext{
+		JobContainer: &container.HostEnvironment{},
+		Config: &Config{
+			Secrets: map[string]string{"TOKEN": "s3cr3t"},
+		},
+		Masks: []string{"masked"},
+	}
+	rc := &RunContext{
+		JobContainer: parent.JobContainer,
+		Config:       parent.Config,
+		Masks:        parent.Masks,
+		Parent:       parent,
+	}
+
+	require.NoError(t, os.WriteFile(summaryPath, []byte("# Build\ntoken: s3cr3t"), 0o600))
+	require.NoError(t, parent.collectStepSummary(ctx, actPath, "workflow/SUMMARY.md"))
+
+	content, err := os.ReadFile(summaryPath)
+	require.NoError(t, err)
+	assert.Empty(t, content)
+
+	// steps of composite actions are added to the summary of the job
+	require.NoError(t, os.WriteFile(summaryPath, []byte("value: masked\n"), 0o600))
+	require.NoError(t, rc.collectStepSummary(ctx, actPath, "workflow/SUMMARY.md"))
+	assert.Equal(t, "# Build\ntoken: ***\nvalue: ***\n", parent.Summary)
+	assert.Empty(t, rc.Summary)
+
+	// an empty summary is ignored
+	require.NoError(t, rc.collectStepSummary(ctx, actPath, "workflow/SUMMARY.md"))
+	assert.Equal(t, "# Build\ntoken: ***\nvalue: ***\n", parent.Summary)
+
+	// a summary exceeding the limit is skipped
+	require.NoError(t, os.WriteFile(summaryPath, []byte(strings.Repeat("x", maxStepSummarySize+1)), 0o600))
+	require.NoError(t, rc.collectStepSummary(ctx, actPath, "workflow/SUMMARY.md"))
+	assert.Equal(t, "# Build\ntoken: ***\nvalue: ***\n", parent.Summary)
+}
+
+func TestReportJobSummary(t *testing.T) {
+	var reported []string
+	rc := &RunContext{
+		Name: "Build",
+		Config: &Config{
+			JobSummaryHandler: func(jobID, jobName, summary string) {
+				reported = append(reported, jobID, jobName, summary)
+			},
+		},
+		Run: &model.Run{
+			JobID: "build",
+			Workflow: &model.Workflow{
+				Name: "CI",
+				Jobs: map[string]*model.Job{
+					"build": {},
+				},
+			},
+		},
+	}
+
+	reportJobSummary(rc)
+	assert.Empty(t, reported)
+
+	rc.Summary = "# Build\n"
+	reportJobSummary(rc)
+	assert.Equal(t, []string{"build", "CI/Build", "# Build\n"}, reported)
+}